@@ -0,0 +1,142 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+// alertState records the agents with an open alert, so scheduled checks alert
+// once when an agent goes down and once when it recovers, rather than on
+// every run. It is read from ALERT_STATE_FILE, which is cached between runs.
+type alertState struct {
+	Alerts map[string]openAlert `json:"alerts"`
+}
+
+type openAlert struct {
+	Since    time.Time `json:"since"`
+	LastSent time.Time `json:"last_sent"`
+}
+
+var (
+	// alertStateFile is ALERT_STATE_FILE, alerts are not deduplicated without it
+	alertStateFile string
+	// alertRepeatAfter is how long an open alert waits to be sent again, or
+	// zero to only send it once
+	alertRepeatAfter time.Duration
+	alerts           *alertState
+)
+
+// loadAlertState reads the state file, returning an empty state when it does
+// not exist yet.
+func loadAlertState(path string) (*alertState, error) {
+	state := &alertState{Alerts: make(map[string]openAlert)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Alerts == nil {
+		state.Alerts = make(map[string]openAlert)
+	}
+	return state, nil
+}
+
+func (s *alertState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// alertAgent sends an alert for the agent, with the end of its runtime log,
+// unless one is already open in the alert state and ALERT_REPEAT_AFTER has not
+// passed since it was sent.
+func alertAgent(client *cloudagents.Client, agent *livekit.AgentInfo, agentID string, message string) {
+	if alerts == nil {
+		notify(agentAlert(client, agent, agentID, message))
+		return
+	}
+
+	now := time.Now()
+	open, ok := alerts.Alerts[agentID]
+	if ok && (alertRepeatAfter == 0 || now.Sub(open.LastSent) < alertRepeatAfter) {
+		log.Infow("Alert already sent, not repeating it", "agent", agentID, "since", open.Since)
+		return
+	}
+	if !ok {
+		open.Since = now
+	}
+	notify(agentAlert(client, agent, agentID, message))
+	open.LastSent = now
+	alerts.Alerts[agentID] = open
+	if err := alerts.save(alertStateFile); err != nil {
+		log.Warnw("Failed to save alert state", err, "path", alertStateFile)
+	}
+}
+
+func agentAlert(client *cloudagents.Client, agent *livekit.AgentInfo, agentID string, message string) notification {
+	n := notification{Event: eventAlert, Message: message, Agent: agent, AgentID: agentID}
+	if notifyLogLines > 0 && notificationsConfigured() {
+		lines, err := fetchRuntimeLog(client, agentID, notifyLogLines)
+		if err != nil {
+			log.Warnw("Failed to fetch agent logs", err)
+		}
+		n.Log = lines
+	}
+	return n
+}
+
+// agentRecovered resolves the agent's PagerDuty incident, and sends a single
+// recovered notification when the alert state has an open alert for it.
+func agentRecovered(agent *livekit.AgentInfo, agentID string) {
+	resolvePagerDutyAlert(agentID)
+	if alerts == nil {
+		return
+	}
+	open, ok := alerts.Alerts[agentID]
+	if !ok {
+		return
+	}
+	downtime := time.Since(open.Since).Round(time.Second)
+	notify(notification{
+		Event:    eventRecovered,
+		Message:  fmt.Sprintf("Agent %s recovered after %s", agentID, downtime),
+		Agent:    agent,
+		AgentID:  agentID,
+		Duration: downtime,
+	})
+	delete(alerts.Alerts, agentID)
+	if err := alerts.save(alertStateFile); err != nil {
+		log.Warnw("Failed to save alert state", err, "path", alertStateFile)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+// deployChanges is what a deploy changed on the agent, for notifications.
+type deployChanges struct {
+	SecretsAdded   []string
+	SecretsRemoved []string
+	// regionsBefore are the agent's regions before the deploy, compared with
+	// the agent in notifications. nil when they could not be fetched.
+	regionsBefore []string
+	// PreviousCommit is the commit of the last successful deploy, from the
+	// GitHub deployments.
+	PreviousCommit string
+}
+
+// changes is recorded by deployAgent, when notifications are configured.
+var changes deployChanges
+
+// notificationsConfigured reports whether any notifier is configured.
+func notificationsConfigured() bool {
+	for _, nt := range notifiers {
+		if nt.configured() {
+			return true
+		}
+	}
+	return false
+}
+
+// recordDeployChanges records what deploying the secrets in secretsMode will
+// change, before the deploy. Anything that cannot be fetched is left out of
+// the notifications with a warning.
+func recordDeployChanges(client *cloudagents.Client, agentID string, secrets []*livekit.AgentSecret, secretsMode string, workingDir string) {
+	ctx := context.Background()
+	res, err := client.ListAgents(ctx, &livekit.ListAgentsRequest{AgentId: agentID})
+	if err != nil {
+		log.Warnw("Failed to get agent, regions changed are left out of notifications", err)
+	} else if len(res.Agents) > 0 {
+		changes.regionsBefore = []string{}
+		for _, deployment := range res.Agents[0].AgentDeployments {
+			changes.regionsBefore = append(changes.regionsBefore, deployment.Region)
+		}
+	}
+
+	// the deploy only replaces the secrets when it carries some
+	if len(secrets) > 0 {
+		res, err := client.ListAgentSecrets(ctx, &livekit.ListAgentSecretsRequest{AgentId: agentID})
+		if err != nil {
+			log.Warnw("Failed to list agent secrets, secrets changed are left out of notifications", err)
+		} else {
+			added, removed, _ := diffSecretNames(secrets, res.Secrets)
+			changes.SecretsAdded = added
+			if secretsMode != "merge" {
+				changes.SecretsRemoved = removed
+			}
+		}
+	}
+
+	// previous deploys are only known from the GitHub deployments
+	if getBoolInput("GITHUB_DEPLOYMENT") {
+		gh, err := newGitHubClient()
+		if err != nil {
+			log.Warnw("Failed to create GitHub client, commits deployed are left out of notifications", err)
+			return
+		}
+		previous, err := lastDeployedCommit(ctx, gh, deploymentEnvironment(), filepath.ToSlash(filepath.Clean(workingDir)))
+		if err != nil {
+			log.Warnw("Failed to find the last deployed commit, commits deployed are left out of notifications", err)
+			return
+		}
+		changes.PreviousCommit = previous
+	}
+}
+
+// notificationChanges is what the deploy changed, available to templates as
+// .Changes. Every field is empty for operations other than deploys.
+type notificationChanges struct {
+	SecretsAdded   []string
+	SecretsRemoved []string
+	RegionsAdded   []string
+	RegionsRemoved []string
+	// PreviousCommit is the commit deployed before, and CompareURL the
+	// commits since then, when GITHUB_DEPLOYMENT records deploys.
+	PreviousCommit string
+	CompareURL     string
+}
+
+// notificationLinks are links for the notification, available to templates
+// as .Links.
+type notificationLinks struct {
+	Dashboard string
+	Compare   string
+	Commit    string
+	Run       string
+}
+
+// changesFor returns the changes to report with the agent as it is now.
+func changesFor(agent *livekit.AgentInfo, commit string) notificationChanges {
+	c := notificationChanges{
+		SecretsAdded:   changes.SecretsAdded,
+		SecretsRemoved: changes.SecretsRemoved,
+	}
+	if agent != nil && changes.regionsBefore != nil {
+		var after []string
+		for _, deployment := range agent.AgentDeployments {
+			after = append(after, deployment.Region)
+			if !slices.Contains(changes.regionsBefore, deployment.Region) {
+				c.RegionsAdded = append(c.RegionsAdded, deployment.Region)
+			}
+		}
+		for _, region := range changes.regionsBefore {
+			if !slices.Contains(after, region) {
+				c.RegionsRemoved = append(c.RegionsRemoved, region)
+			}
+		}
+	}
+	if changes.PreviousCommit != "" && changes.PreviousCommit != commit {
+		c.PreviousCommit = changes.PreviousCommit
+		server := os.Getenv("GITHUB_SERVER_URL")
+		if repository := os.Getenv("GITHUB_REPOSITORY"); server != "" && repository != "" && commit != "" {
+			c.CompareURL = fmt.Sprintf("%s/%s/compare/%s...%s", server, repository, c.PreviousCommit, commit)
+		}
+	}
+	return c
+}
+
+// changeSummary lists what the deploy changed, for finished operations, with
+// link rendering a link in the channel's markup.
+func (d notificationData) changeSummary(link func(text, url string) string) string {
+	if d.Event != eventSucceeded && d.Event != eventFailed {
+		return ""
+	}
+	var lines []string
+	list := func(name string, values []string) {
+		if len(values) > 0 {
+			lines = append(lines, fmt.Sprintf("• %s: %s", name, strings.Join(values, ", ")))
+		}
+	}
+	list("Secrets added", d.Changes.SecretsAdded)
+	list("Secrets removed", d.Changes.SecretsRemoved)
+	list("Regions added", d.Changes.RegionsAdded)
+	list("Regions removed", d.Changes.RegionsRemoved)
+	if d.Changes.PreviousCommit != "" {
+		commits := fmt.Sprintf("%s...%s", shortCommit(d.Changes.PreviousCommit), d.Git.ShortCommit)
+		if d.Changes.CompareURL != "" {
+			commits = link(commits, d.Changes.CompareURL)
+		}
+		lines = append(lines, "• Commits: "+commits)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
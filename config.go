@@ -221,6 +221,11 @@ var (
 	ErrInvalidReplicaCount = fmt.Errorf("replicas cannot be greater than max_replicas: %w", ErrInvalidConfig)
 )
 
+var (
+	subdomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	regionPattern    = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)+$`)
+)
+
 // Deprecated: use LiveKitTOML instead
 type AgentTOML struct {
 	ProjectSubdomain string   `toml:"project_subdomain"`
@@ -237,8 +242,21 @@ type LiveKitTOMLProjectConfig struct {
 }
 
 type LiveKitTOMLAgentConfig struct {
-	ID      string   `toml:"id"`
-	Regions []string `toml:"regions"`
+	ID          string   `toml:"id"`
+	Name        string   `toml:"name,omitempty"`
+	Regions     []string `toml:"regions"`
+	Replicas    int      `toml:"replicas,omitempty"`
+	MaxReplicas int      `toml:"max_replicas,omitempty"`
+	// RequiredSecrets are checked to be provided before deploying
+	RequiredSecrets []string `toml:"required_secrets,omitempty"`
+}
+
+// ValidateReplicas checks the replica counts, where zero means unset.
+func ValidateReplicas(replicas, maxReplicas int) error {
+	if maxReplicas > 0 && replicas > maxReplicas {
+		return ErrInvalidReplicaCount
+	}
+	return nil
 }
 
 func NewLiveKitTOML(forSubdomain string) *LiveKitTOML {
@@ -261,6 +279,57 @@ func (c *LiveKitTOML) HasAgent() bool {
 	return c.Agent != nil
 }
 
+// Validate checks the configuration for missing or malformed fields.
+// Every problem found is returned, each wrapping ErrInvalidConfig.
+func (c *LiveKitTOML) Validate() []error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), ErrInvalidConfig))
+	}
+
+	if c.Project == nil {
+		invalid("missing [project] section")
+	} else if c.Project.Subdomain == "" {
+		invalid("project.subdomain is required")
+	} else if !subdomainPattern.MatchString(c.Project.Subdomain) {
+		invalid("project.subdomain %q must contain only lowercase letters, digits and hyphens", c.Project.Subdomain)
+	}
+
+	if c.Agent == nil {
+		invalid("missing [agent] section")
+		return errs
+	}
+
+	if c.Agent.ID == "" {
+		invalid("agent.id is required")
+	} else if !strings.HasPrefix(c.Agent.ID, "CA_") {
+		invalid("agent.id %q must start with CA_", c.Agent.ID)
+	}
+
+	seen := make(map[string]bool)
+	for _, region := range c.Agent.Regions {
+		if !regionPattern.MatchString(region) {
+			invalid("agent.regions entry %q is not a valid region name (e.g. us-east)", region)
+		}
+		if seen[region] {
+			invalid("agent.regions entry %q is duplicated", region)
+		}
+		seen[region] = true
+	}
+
+	if c.Agent.Replicas < 0 {
+		invalid("agent.replicas cannot be negative")
+	}
+	if c.Agent.MaxReplicas < 0 {
+		invalid("agent.max_replicas cannot be negative")
+	}
+	if err := ValidateReplicas(c.Agent.Replicas, c.Agent.MaxReplicas); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
 func (c *LiveKitTOML) SaveTOMLFile(dir string, tomlFileName string) error {
 	f, err := os.Create(filepath.Join(dir, tomlFileName))
 	if err != nil {
@@ -287,6 +356,9 @@ func LoadTOMLFile(dir string, tomlFileName string) (*LiveKitTOML, bool, error) {
 		configExists = true
 
 		_, err = toml.DecodeFile(tomlFile, &config)
+		if err != nil {
+			return nil, configExists, err
+		}
 		if config.Project == nil {
 			// Attempt to decode old agent config
 			var oldConfig AgentTOML
@@ -0,0 +1,142 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Discord limits, longer embeds are rejected.
+const (
+	maxDiscordDescription = 4096
+	maxDiscordFieldValue  = 1024
+)
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// discordNotifier posts notifications to DISCORD_WEBHOOK_URL as embeds with
+// the same details as the Slack message. Progress updates are not sent, as
+// Discord webhooks cannot thread them.
+type discordNotifier struct{}
+
+func (discordNotifier) name() string { return "discord" }
+
+func (discordNotifier) configured() bool { return getInputOrEnv("DISCORD_WEBHOOK_URL") != "" }
+
+func (discordNotifier) supports(event string) bool { return event != eventProgress }
+
+func (discordNotifier) send(n notification) error {
+	payload := map[string]any{
+		"username": "LiveKit Cloud",
+		"embeds":   []discordEmbed{discordNotificationEmbed(n.text(), n.data())},
+	}
+	return postWebhook(getInputOrEnv("DISCORD_WEBHOOK_URL"), payload, "")
+}
+
+func discordNotificationEmbed(text string, d notificationData) discordEmbed {
+	embed := discordEmbed{
+		Title:       fmt.Sprintf("LiveKit Cloud agent %s %s", d.Operation, d.Event),
+		Description: truncate(text, maxDiscordDescription),
+		URL:         d.Git.RunURL,
+		Color:       discordColor(d.Event),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+	field := func(name, value string) {
+		if value != "" {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: name, Value: truncate(value, maxDiscordFieldValue), Inline: true})
+		}
+	}
+	if d.Agent.ID != "" {
+		field("Agent", fmt.Sprintf("`%s`", d.Agent.ID))
+	}
+	if d.Agent.Version != "" {
+		field("Version", fmt.Sprintf("`%s`", d.Agent.Version))
+	}
+	field("Operation", d.Operation)
+	field("Environment", d.Environment)
+	if d.Git.CommitURL != "" {
+		field("Commit", fmt.Sprintf("[%s](%s)", d.Git.ShortCommit, d.Git.CommitURL))
+	} else if d.Git.ShortCommit != "" {
+		field("Commit", fmt.Sprintf("`%s`", d.Git.ShortCommit))
+	}
+	field("Actor", d.Git.Actor)
+	if d.Duration > 0 {
+		field("Duration", d.Duration.String())
+	}
+
+	if len(d.Agent.Regions) > 0 {
+		var regions []string
+		for _, region := range d.Agent.Regions {
+			regions = append(regions, fmt.Sprintf("• %s: %s (%d / %d replicas)", region.Region, region.Status, region.Replicas, region.MaxReplicas))
+		}
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Regions", Value: truncate(strings.Join(regions, "\n"), maxDiscordFieldValue)})
+	}
+	if summary := d.changeSummary(markdownLink); summary != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Changes", Value: truncate(summary, maxDiscordFieldValue)})
+	}
+	if summary := d.phaseSummary(); summary != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Phases", Value: truncate(summary, maxDiscordFieldValue)})
+	}
+	if len(d.Log) > 0 {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Log", Value: "```\n" + d.logExcerpt(maxDiscordFieldValue-10) + "\n```"})
+	}
+	var links []string
+	if d.Git.RunURL != "" {
+		links = append(links, markdownLink("View workflow run", d.Git.RunURL))
+	}
+	links = append(links, markdownLink("LiveKit Cloud dashboard", d.Links.Dashboard))
+	embed.Fields = append(embed.Fields, discordEmbedField{Name: "Links", Value: strings.Join(links, " · ")})
+	return embed
+}
+
+func discordColor(event string) int {
+	switch event {
+	case eventSucceeded, eventRecovered:
+		return 0x2eb67d
+	case eventFailed, eventAlert:
+		return 0xe01e5a
+	default:
+		return 0x5865f2
+	}
+}
+
+// truncate shortens s to at most limit runes, marking that it was cut.
+// markdownLink renders a link in the markdown Discord and Teams use.
+func markdownLink(text, url string) string {
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// githubClient calls the GitHub REST API for the current repository.
+type githubClient struct {
+	token   string
+	baseURL string
+	repo    string
+}
+
+func newGitHubClient() (*githubClient, error) {
+	token, err := githubToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN or GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY are required")
+	}
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if repo == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY is not set")
+	}
+	baseURL := os.Getenv("GITHUB_API_URL")
+	if baseURL == "" {
+		baseURL = defaultGitHubAPIURL
+	}
+	return &githubClient{token: token, baseURL: strings.TrimSuffix(baseURL, "/"), repo: repo}, nil
+}
+
+// githubAPIError is returned by do for responses outside of 2xx.
+type githubAPIError struct {
+	method, path, status string
+	StatusCode           int
+	body                 []byte
+}
+
+func (e *githubAPIError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.method, e.path, e.status, e.body)
+}
+
+// isGitHubStatus reports whether err is a GitHub API response with the status code.
+func isGitHubStatus(err error, code int) bool {
+	var apiErr *githubAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// do sends a request to a path under /repos/{owner}/{repo}, encoding body and
+// decoding the response into out when they are not nil.
+func (c *githubClient) do(ctx context.Context, method string, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/repos/%s%s", c.baseURL, c.repo, path), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return &githubAPIError{method: method, path: path, status: resp.Status, StatusCode: resp.StatusCode, body: data}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// maxCompareFiles is the most files GitHub lists when comparing commits.
+const maxCompareFiles = 300
+
+// changedFiles lists the files changed from base to head. complete is false
+// when the list cannot be relied on: head is not ahead of base, so the
+// comparison does not show what changed since base, or GitHub truncated it.
+func changedFiles(ctx context.Context, client *githubClient, base string, head string) (files []string, complete bool, err error) {
+	var comparison struct {
+		Status string `json:"status"`
+		Files  []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+		} `json:"files"`
+	}
+	if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/compare/%s...%s", base, head), nil, &comparison); err != nil {
+		return nil, false, fmt.Errorf("failed to compare commits: %w", err)
+	}
+	switch comparison.Status {
+	case "identical":
+		return nil, true, nil
+	case "ahead":
+	default:
+		return nil, false, nil
+	}
+	if len(comparison.Files) >= maxCompareFiles {
+		return nil, false, nil
+	}
+
+	for _, file := range comparison.Files {
+		files = append(files, file.Filename)
+		if file.PreviousFilename != "" {
+			files = append(files, file.PreviousFilename)
+		}
+	}
+	return files, true, nil
+}
+
+// githubEvent is the part of the webhook payload that triggered the workflow
+// that the action uses.
+type githubEvent struct {
+	PullRequest *struct {
+		Number int `json:"number"`
+		Head   struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Release *struct {
+		TagName string `json:"tag_name"`
+	} `json:"release"`
+}
+
+func loadGitHubEvent() *githubEvent {
+	event := &githubEvent{}
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return event
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return event
+	}
+	_ = json.Unmarshal(data, event)
+	return event
+}
+
+// pullRequestNumber is the number of the pull request that triggered the
+// workflow, or 0 for other events.
+func pullRequestNumber() int {
+	if event := loadGitHubEvent(); event.PullRequest != nil {
+		return event.PullRequest.Number
+	}
+	return 0
+}
+
+// commitSHA is the commit being deployed. On pull requests GITHUB_SHA is the
+// test merge commit, so the head of the pull request is used instead.
+func commitSHA() string {
+	if event := loadGitHubEvent(); event.PullRequest != nil && event.PullRequest.Head.SHA != "" {
+		return event.PullRequest.Head.SHA
+	}
+	return os.Getenv("GITHUB_SHA")
+}
+
+// upsertPullRequestComment updates the comment posted by a previous run for
+// the same key, or creates one, so each pull request has a single comment per agent.
+func upsertPullRequestComment(ctx context.Context, client *githubClient, number int, key string, body string) error {
+	marker := fmt.Sprintf("<!-- livekit-deploy-action:%s -->", key)
+	body = marker + "\n" + body
+
+	for page := 1; ; page++ {
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/issues/%d/comments?per_page=100&page=%d", number, page), nil, &comments); err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.Body, marker) {
+				return client.do(ctx, http.MethodPatch, fmt.Sprintf("/issues/comments/%d", comment.ID), map[string]string{"body": body}, nil)
+			}
+		}
+		if len(comments) < 100 {
+			break
+		}
+	}
+	return client.do(ctx, http.MethodPost, fmt.Sprintf("/issues/%d/comments", number), map[string]string{"body": body}, nil)
+}
+
+// stepOutputs records every output set, for namespaced outputs.
+var stepOutputs = map[string]string{}
+
+// setOutput sets a step output through the runner's GITHUB_OUTPUT file. It
+// is a no-op outside of GitHub Actions.
+func setOutput(name string, value string) error {
+	stepOutputs[name] = value
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+
+	var entry string
+	if strings.ContainsAny(value, "\r\n") {
+		delimiter := make([]byte, 16)
+		if _, err := rand.Read(delimiter); err != nil {
+			return err
+		}
+		eof := "EOF_" + hex.EncodeToString(delimiter)
+		entry = fmt.Sprintf("%s<<%s\n%s\n%s\n", name, eof, value, eof)
+	} else {
+		entry = fmt.Sprintf("%s=%s\n", name, value)
+	}
+	return appendFile(path, entry)
+}
+
+// appendStepSummary adds markdown to the job summary through the runner's
+// GITHUB_STEP_SUMMARY file. It is a no-op outside of GitHub Actions.
+func appendStepSummary(markdown string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	return appendFile(path, markdown)
+}
+
+func appendFile(path string, data string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(data)
+	return err
+}
+
+// phase is a log group that has ended, with how long it took.
+type phase struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// buildGroup is the log group of the package, upload and build step, which
+// failure notifications check for before including the build log.
+const buildGroup = "Package, upload and build agent"
+
+var (
+	// groupOpen is set while a log group is open, since groups cannot be nested.
+	groupOpen  bool
+	groupTitle string
+	groupStart time.Time
+	phases     []phase
+)
+
+// startGroup starts a collapsible group in the workflow log, ending the
+// current one. The markers are written to stderr with the logs, so they stay
+// in order with the lines they group.
+func startGroup(title string) {
+	endGroup()
+	fmt.Fprintf(os.Stderr, "::group::%s\n", escapeWorkflowCommand(title))
+	groupOpen = true
+	groupTitle = title
+	groupStart = time.Now()
+}
+
+// buildStarted reports whether the build group was started in this run.
+func buildStarted() bool {
+	if groupOpen && groupTitle == buildGroup {
+		return true
+	}
+	for _, p := range phases {
+		if p.Name == buildGroup {
+			return true
+		}
+	}
+	return false
+}
+
+func endGroup() {
+	if groupOpen {
+		fmt.Fprintln(os.Stderr, "::endgroup::")
+		groupOpen = false
+		phases = append(phases, phase{
+			Name:            groupTitle,
+			DurationSeconds: time.Since(groupStart).Round(time.Millisecond).Seconds(),
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	appTokenMu sync.Mutex
+	// appToken is the installation token minted for the run, reused by every client
+	appToken string
+)
+
+// githubToken returns the token used for the GitHub API: an installation
+// token for the GitHub App when GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY are
+// set, and GITHUB_TOKEN otherwise.
+func githubToken() (string, error) {
+	appID := strings.TrimSpace(os.Getenv("INPUT_GITHUB_APP_ID"))
+	privateKey := os.Getenv("INPUT_GITHUB_APP_PRIVATE_KEY")
+	if appID == "" && privateKey == "" {
+		return os.Getenv("INPUT_GITHUB_TOKEN"), nil
+	}
+	if appID == "" || privateKey == "" {
+		return "", errors.New("GITHUB_APP_ID and GITHUB_APP_PRIVATE_KEY must be set together")
+	}
+
+	appTokenMu.Lock()
+	defer appTokenMu.Unlock()
+	if appToken != "" {
+		return appToken, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	token, err := mintInstallationToken(ctx, appID, privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitHub App installation token: %w", err)
+	}
+	maskValue(token)
+	logScrubber.add(token)
+	appToken = token
+	return token, nil
+}
+
+// mintInstallationToken signs an app JWT with the private key, looks up the
+// app's installation on the repository and creates a token for it.
+func mintInstallationToken(ctx context.Context, appID string, privateKey string) (string, error) {
+	if _, err := strconv.ParseInt(appID, 10, 64); err != nil {
+		return "", fmt.Errorf("GITHUB_APP_ID %q is not a number", appID)
+	}
+	key, err := parseAppPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	jwt, err := signAppJWT(appID, key, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if repo == "" {
+		return "", errors.New("GITHUB_REPOSITORY is not set")
+	}
+	baseURL := os.Getenv("GITHUB_API_URL")
+	if baseURL == "" {
+		baseURL = defaultGitHubAPIURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := appRequest(ctx, jwt, http.MethodGet, baseURL+"/repos/"+repo+"/installation", &installation); err != nil {
+		return "", fmt.Errorf("the app is not installed on %s: %w", repo, err)
+	}
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := appRequest(ctx, jwt, http.MethodPost, fmt.Sprintf("%s/app/installations/%d/access_tokens", baseURL, installation.ID), &token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		return "", errors.New("no token in response")
+	}
+	return token.Token, nil
+}
+
+// parseAppPrivateKey reads the PEM private key GitHub generates for an app,
+// PKCS#1, or PKCS#8 if it was converted.
+func parseAppPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(privateKey)))
+	if block == nil {
+		return nil, errors.New("GITHUB_APP_PRIVATE_KEY is not a PEM encoded key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("GITHUB_APP_PRIVATE_KEY is not a valid RSA private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GITHUB_APP_PRIVATE_KEY is not an RSA private key")
+	}
+	return key, nil
+}
+
+// signAppJWT creates the RS256 JWT that authenticates as the app. GitHub
+// accepts at most 10 minutes of validity, and iat is backdated for clock skew.
+func signAppJWT(appID string, key *rsa.PrivateKey, now time.Time) (string, error) {
+	encode := func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := encode(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := header + "." + claims
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appRequest calls an app endpoint, which are outside of the repository paths
+// githubClient.do is limited to.
+func appRequest(ctx context.Context, jwt string, method string, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return &githubAPIError{method: method, path: req.URL.Path, status: resp.Status, StatusCode: resp.StatusCode, body: data}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
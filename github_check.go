@@ -0,0 +1,123 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+const (
+	defaultCheckRunName = "LiveKit Cloud Agent"
+	// checkRunLogLines is how much of the build log is attached to the check run
+	checkRunLogLines = 200
+	// maxCheckRunText is GitHub's limit on the size of a check run's output text
+	maxCheckRunText = 65535
+)
+
+// githubCheckRun is a check run on the deployed commit, created when an
+// operation starts and completed with its result when the action exits.
+type githubCheckRun struct {
+	client *githubClient
+	id     int64
+	name   string
+}
+
+func startGitHubCheckRun(ctx context.Context, client *githubClient, name string) (*githubCheckRun, error) {
+	sha := commitSHA()
+	if sha == "" {
+		return nil, fmt.Errorf("GITHUB_SHA is not set")
+	}
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/check-runs", map[string]any{
+		"name":        name,
+		"head_sha":    sha,
+		"status":      "in_progress",
+		"details_url": workflowRunURL(),
+		"started_at":  time.Now().UTC().Format(time.RFC3339),
+	}, &created); err != nil {
+		return nil, fmt.Errorf("failed to create check run: %w", err)
+	}
+	return &githubCheckRun{client: client, id: created.ID, name: name}, nil
+}
+
+// complete sets the check run's conclusion, with the summary as its output
+// and the build log tail as its details.
+func (r *githubCheckRun) complete(ctx context.Context, success bool, summary string, buildLog []string) error {
+	conclusion, title := "success", r.name+" succeeded"
+	if !success {
+		conclusion, title = "failure", r.name+" failed"
+	}
+
+	output := map[string]any{
+		"title":   title,
+		"summary": summary,
+	}
+	if len(buildLog) > 0 {
+		output["text"] = formatCheckRunLog(buildLog)
+	}
+
+	if err := r.client.do(ctx, http.MethodPatch, fmt.Sprintf("/check-runs/%d", r.id), map[string]any{
+		"status":       "completed",
+		"conclusion":   conclusion,
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+		"output":       output,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to complete check run: %w", err)
+	}
+	return nil
+}
+
+// formatCheckRunLog renders log lines as a code block, dropping the oldest
+// lines until it fits in a check run's output.
+func formatCheckRunLog(lines []string) string {
+	const header = "#### Build log\n\n```\n"
+	const footer = "\n```\n"
+	text := strings.Join(lines, "\n")
+	for len(header)+len(text)+len(footer) > maxCheckRunText && len(lines) > 1 {
+		lines = lines[1:]
+		text = strings.Join(lines, "\n")
+	}
+	if len(header)+len(text)+len(footer) > maxCheckRunText {
+		text = text[len(text)-(maxCheckRunText-len(header)-len(footer)):]
+	}
+	return header + text + footer
+}
+
+// fetchBuildLog fetches the agent's most recent build log, with secret values
+// scrubbed.
+func fetchBuildLog(client *cloudagents.Client, agentID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	w := newTailWriter(0)
+	err := streamLogs(ctx, client, "build", agentID, "", w)
+	lines := w.Lines()
+	for i, line := range lines {
+		lines[i] = logScrubber.scrub(line)
+	}
+	if err != nil && ctx.Err() == nil {
+		return lines, err
+	}
+	return lines, nil
+}
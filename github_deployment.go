@@ -0,0 +1,218 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// githubDeployment tracks a GitHub Deployment for the target environment, so
+// the Deployments tab shows what is live.
+type githubDeployment struct {
+	client      *githubClient
+	id          int64
+	environment string
+}
+
+// deploymentPayload is attached to each deployment, so a re-run of the same
+// workflow run can tell that it already deployed.
+type deploymentPayload struct {
+	RunID            string `json:"run_id"`
+	RunAttempt       string `json:"run_attempt,omitempty"`
+	Operation        string `json:"operation"`
+	WorkingDirectory string `json:"working_directory"`
+	// Release is the released version deployed, for tag and release events
+	Release string `json:"release,omitempty"`
+	// SourceHash is the hash of the uploaded archive, for operations that
+	// upload the source
+	SourceHash string `json:"source_hash,omitempty"`
+}
+
+// startGitHubDeployment creates a deployment of the current commit and marks
+// it in progress.
+func startGitHubDeployment(ctx context.Context, client *githubClient, environment string, description string, payload deploymentPayload) (*githubDeployment, error) {
+	ref := commitSHA()
+	if ref == "" {
+		return nil, fmt.Errorf("GITHUB_SHA is not set")
+	}
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/deployments", map[string]any{
+		"ref":         ref,
+		"environment": environment,
+		"description": description,
+		"auto_merge":  false,
+		"payload":     payload,
+		// the workflow running this action is the check that matters
+		"required_contexts": []string{},
+	}, &created); err != nil {
+		return nil, fmt.Errorf("failed to create GitHub deployment: %w", err)
+	}
+
+	d := &githubDeployment{client: client, id: created.ID, environment: environment}
+	if err := d.setStatus(ctx, "in_progress", "Deploying to LiveKit Cloud"); err != nil {
+		return nil, err
+	}
+	log.Infow("GitHub deployment created", "deployment", d.id, "environment", environment)
+	return d, nil
+}
+
+// setStatus records a deployment status: in_progress, success or failure.
+func (d *githubDeployment) setStatus(ctx context.Context, state string, description string) error {
+	body := map[string]any{
+		"state":           state,
+		"description":     description,
+		"environment":     d.environment,
+		"environment_url": liveKitCloudURL,
+		"auto_inactive":   true,
+	}
+	if url := workflowRunURL(); url != "" {
+		body["log_url"] = url
+	}
+	if err := d.client.do(ctx, http.MethodPost, fmt.Sprintf("/deployments/%d/statuses", d.id), body, nil); err != nil {
+		return fmt.Errorf("failed to update GitHub deployment status: %w", err)
+	}
+	return nil
+}
+
+// findSuccessfulDeployment returns the ID of a successful deployment of the
+// current commit to environment made by the same workflow run, operation and
+// working directory as payload, or 0 when there is none.
+func findSuccessfulDeployment(ctx context.Context, client *githubClient, environment string, payload deploymentPayload) (int64, error) {
+	sha := commitSHA()
+	if sha == "" || payload.RunID == "" {
+		return 0, fmt.Errorf("GITHUB_SHA and GITHUB_RUN_ID must be set")
+	}
+
+	query := url.Values{}
+	query.Set("sha", sha)
+	query.Set("environment", environment)
+	query.Set("per_page", "100")
+	var deployments []struct {
+		ID      int64           `json:"id"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/deployments?"+query.Encode(), nil, &deployments); err != nil {
+		return 0, fmt.Errorf("failed to list GitHub deployments: %w", err)
+	}
+
+	for _, deployment := range deployments {
+		var previous deploymentPayload
+		if err := json.Unmarshal(deployment.Payload, &previous); err != nil {
+			continue
+		}
+		if previous.RunID != payload.RunID || previous.Operation != payload.Operation || previous.WorkingDirectory != payload.WorkingDirectory {
+			continue
+		}
+
+		// statuses are listed newest first
+		var statuses []struct {
+			State string `json:"state"`
+		}
+		if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/deployments/%d/statuses?per_page=1", deployment.ID), nil, &statuses); err != nil {
+			return 0, fmt.Errorf("failed to list GitHub deployment statuses: %w", err)
+		}
+		if len(statuses) > 0 && statuses[0].State == "success" {
+			return deployment.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// deployedVersionDescription is the description of a successful deployment
+// status, recording the agent version that was live after the deploy. The
+// payload is fixed when the deployment is created, so the version can only be
+// recorded on its final status.
+const deployedVersionDescription = "Deployed version %s to LiveKit Cloud"
+
+// deployedVersion returns the agent version recorded in the description of a
+// successful deployment status, or "" when it has none.
+func deployedVersion(description string) string {
+	prefix, suffix, _ := strings.Cut(deployedVersionDescription, "%s")
+	version, ok := strings.CutPrefix(description, prefix)
+	if !ok {
+		return ""
+	}
+	version, ok = strings.CutSuffix(version, suffix)
+	if !ok {
+		return ""
+	}
+	return version
+}
+
+// previousDeployment is a successful deployment of a working directory.
+type previousDeployment struct {
+	ID      int64
+	SHA     string
+	Payload deploymentPayload
+	// Version is the agent version live after the deployment, "" when it
+	// was not recorded
+	Version string
+}
+
+// lastSuccessfulDeployment returns the newest successful deployment of
+// workingDir to environment, or nil when there is none.
+func lastSuccessfulDeployment(ctx context.Context, client *githubClient, environment string, workingDir string) (*previousDeployment, error) {
+	query := url.Values{}
+	query.Set("environment", environment)
+	query.Set("per_page", "100")
+	var deployments []struct {
+		ID      int64           `json:"id"`
+		SHA     string          `json:"sha"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/deployments?"+query.Encode(), nil, &deployments); err != nil {
+		return nil, fmt.Errorf("failed to list GitHub deployments: %w", err)
+	}
+
+	// deployments are listed newest first
+	for _, deployment := range deployments {
+		var payload deploymentPayload
+		if err := json.Unmarshal(deployment.Payload, &payload); err != nil || payload.WorkingDirectory != workingDir {
+			continue
+		}
+
+		var statuses []struct {
+			State       string `json:"state"`
+			Description string `json:"description"`
+		}
+		if err := client.do(ctx, http.MethodGet, fmt.Sprintf("/deployments/%d/statuses?per_page=1", deployment.ID), nil, &statuses); err != nil {
+			return nil, fmt.Errorf("failed to list GitHub deployment statuses: %w", err)
+		}
+		if len(statuses) == 0 || statuses[0].State != "success" {
+			continue
+		}
+		return &previousDeployment{ID: deployment.ID, SHA: deployment.SHA, Payload: payload, Version: deployedVersion(statuses[0].Description)}, nil
+	}
+	return nil, nil
+}
+
+// lastDeployedCommit returns the commit of the newest successful deployment
+// of workingDir to environment, or "" when there is none or the newest one is
+// a rollback, which makes an older version live than its commit.
+func lastDeployedCommit(ctx context.Context, client *githubClient, environment string, workingDir string) (string, error) {
+	deployment, err := lastSuccessfulDeployment(ctx, client, environment, workingDir)
+	if err != nil || deployment == nil || deployment.Payload.Operation == "rollback" {
+		return "", err
+	}
+	return deployment.SHA, nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const defaultCommitStatusContext = "livekit/deploy"
+
+// setCommitStatus posts a commit status for the current commit, so branch
+// protection can require a successful deploy. state is pending, success or failure.
+func setCommitStatus(ctx context.Context, client *githubClient, statusContext string, state string, description string) error {
+	sha := commitSHA()
+	if sha == "" {
+		return fmt.Errorf("GITHUB_SHA is not set")
+	}
+	if err := client.do(ctx, http.MethodPost, "/statuses/"+sha, map[string]any{
+		"state":       state,
+		"context":     statusContext,
+		"description": description,
+		"target_url":  liveKitCloudURL,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to set commit status: %w", err)
+	}
+	return nil
+}
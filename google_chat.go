@@ -0,0 +1,117 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+type googleChatNotifier struct{}
+
+func (googleChatNotifier) name() string { return "google_chat" }
+
+func (googleChatNotifier) configured() bool { return getInputOrEnv("GOOGLE_CHAT_WEBHOOK_URL") != "" }
+
+func (googleChatNotifier) supports(event string) bool { return event != eventProgress }
+
+func (googleChatNotifier) send(n notification) error {
+	text := n.text()
+	payload := map[string]any{
+		"text": text,
+		"cardsV2": []map[string]any{{
+			"cardId": "livekit-cloud-agent",
+			"card":   googleChatCard(text, n.data()),
+		}},
+	}
+	return postWebhook(getInputOrEnv("GOOGLE_CHAT_WEBHOOK_URL"), payload, "")
+}
+
+// googleChatCard renders the notification as a Google Chat card, with the
+// same fields as the Slack blocks. Card text takes a subset of HTML.
+func googleChatCard(text string, d notificationData) map[string]any {
+	link := func(text, url string) string {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(text))
+	}
+	paragraph := func(text string) map[string]any {
+		return map[string]any{"textParagraph": map[string]string{"text": text}}
+	}
+	lines := func(s string) string { return strings.ReplaceAll(s, "\n", "<br>") }
+
+	widgets := []map[string]any{paragraph(lines(html.EscapeString(text)))}
+	field := func(name, value string) {
+		if value != "" {
+			widgets = append(widgets, map[string]any{"decoratedText": map[string]string{"topLabel": name, "text": value}})
+		}
+	}
+	field("Agent", html.EscapeString(d.Agent.ID))
+	field("Version", html.EscapeString(d.Agent.Version))
+	field("Operation", html.EscapeString(d.Operation))
+	field("Environment", html.EscapeString(d.Environment))
+	if d.Git.CommitURL != "" {
+		field("Commit", link(d.Git.ShortCommit, d.Git.CommitURL))
+	} else {
+		field("Commit", d.Git.ShortCommit)
+	}
+	field("Actor", html.EscapeString(d.Git.Actor))
+	if d.Duration > 0 {
+		field("Duration", d.Duration.String())
+	}
+	sections := []map[string]any{{"widgets": widgets}}
+
+	section := func(header, body string) {
+		if body != "" {
+			sections = append(sections, map[string]any{
+				"header":  header,
+				"widgets": []map[string]any{paragraph(lines(body))},
+			})
+		}
+	}
+	var regions []string
+	for _, region := range d.Agent.Regions {
+		regions = append(regions, html.EscapeString(fmt.Sprintf("• %s: %s (%d / %d replicas)", region.Region, region.Status, region.Replicas, region.MaxReplicas)))
+	}
+	section("Regions", strings.Join(regions, "\n"))
+	section("Changes", d.changeSummary(link))
+	section("Phases", html.EscapeString(d.phaseSummary()))
+	section("Log", html.EscapeString(d.logExcerpt(4000)))
+
+	var buttons []map[string]any
+	button := func(text, url string) {
+		if url != "" {
+			buttons = append(buttons, map[string]any{
+				"text":    text,
+				"onClick": map[string]any{"openLink": map[string]string{"url": url}},
+			})
+		}
+	}
+	button("View workflow run", d.Git.RunURL)
+	button("Compare commits", d.Links.Compare)
+	button("LiveKit Cloud dashboard", d.Links.Dashboard)
+	if len(buttons) > 0 {
+		sections = append(sections, map[string]any{
+			"widgets": []map[string]any{{"buttonList": map[string]any{"buttons": buttons}}},
+		})
+	}
+
+	return map[string]any{
+		"header": map[string]string{
+			"title":    fmt.Sprintf("LiveKit Cloud agent %s %s", d.Operation, d.Event),
+			"subtitle": d.Environment,
+		},
+		"sections": sections,
+	}
+}
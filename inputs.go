@@ -0,0 +1,178 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Inputs reach the action as INPUT_<NAME> environment variables, always as
+// strings. The parse functions accept the spellings workflows commonly use,
+// and the get*Input wrappers fail the action with an error naming the input.
+
+// parseBool accepts true/false, yes/no, on/off and 1/0, in any case.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not true, false, yes or no", value)
+}
+
+// parseList splits a comma or newline separated value, dropping empty entries.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseDuration accepts Go durations such as 30s, 10m or 1h30m, and a plain
+// number as seconds. Negative durations are rejected.
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		value += "s"
+		if seconds < 0 {
+			return 0, fmt.Errorf("%q is negative", value)
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration such as 30s, 10m or 1h", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q is negative", value)
+	}
+	return d, nil
+}
+
+// sizeUnits are the suffixes parseSize accepts, decimal and binary.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1000,
+	"kb":  1000,
+	"m":   1000 * 1000,
+	"mb":  1000 * 1000,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+}
+
+// parseSize accepts sizes such as 500MB, 1.5GB or 512MiB, and a plain number
+// as bytes.
+func parseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	number := strings.TrimRightFunc(value, func(r rune) bool {
+		return r == ' ' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	})
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(value[len(number):]))]
+	n, err := strconv.ParseFloat(number, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("%q is not a size such as 500MB or 1GiB", value)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%q is negative", value)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// formatSize renders a byte count with decimal units, e.g. 312.4 MB.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1000*1000*1000:
+		return fmt.Sprintf("%.1f GB", float64(n)/1e9)
+	case n >= 1000*1000:
+		return fmt.Sprintf("%.1f MB", float64(n)/1e6)
+	case n >= 1000:
+		return fmt.Sprintf("%.1f kB", float64(n)/1e3)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// getIntInput reads a non-negative integer input, returning 0 when it is not set.
+func getIntInput(name string) int {
+	value := strings.TrimSpace(os.Getenv("INPUT_" + name))
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Errorw("Invalid "+name+", expected a non-negative integer", nil, "value", value)
+		exit(1)
+	}
+	return n
+}
+
+// getBoolInput reads a boolean input, returning false when it is not set.
+func getBoolInput(name string) bool {
+	value := os.Getenv("INPUT_" + name)
+	if strings.TrimSpace(value) == "" {
+		return false
+	}
+	b, err := parseBool(value)
+	if err != nil {
+		log.Errorw("Invalid "+name, err)
+		exit(1)
+	}
+	return b
+}
+
+// getListInput reads a comma or newline separated input, dropping empty entries.
+func getListInput(name string) []string {
+	return parseList(os.Getenv("INPUT_" + name))
+}
+
+// getDurationInput reads a duration input, returning def when it is not set.
+func getDurationInput(name string, def time.Duration) time.Duration {
+	value := os.Getenv("INPUT_" + name)
+	if strings.TrimSpace(value) == "" {
+		return def
+	}
+	d, err := parseDuration(value)
+	if err != nil {
+		log.Errorw("Invalid "+name, err)
+		exit(1)
+	}
+	return d
+}
+
+// getSizeInput reads a size input in bytes, returning def when it is not set.
+func getSizeInput(name string, def int64) int64 {
+	value := os.Getenv("INPUT_" + name)
+	if strings.TrimSpace(value) == "" {
+		return def
+	}
+	n, err := parseSize(value)
+	if err != nil {
+		log.Errorw("Invalid "+name, err)
+		exit(1)
+	}
+	return n
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseList(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a, b,,c", []string{"a", "b", "c"}},
+		{"a\n b \n\nc,d", []string{"a", "b", "c", "d"}},
+	} {
+		if got := parseList(tc.value); !slices.Equal(got, tc.want) {
+			t.Errorf("parseList(%q) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "30", want: 30 * time.Second},
+		{value: " 0 ", want: 0},
+		{value: "10m", want: 10 * time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "-5", wantErr: true},
+		{value: "-1m", wantErr: true},
+		{value: "soon", wantErr: true},
+		{value: "", wantErr: true},
+	} {
+		got, err := parseDuration(tc.value)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseDuration(%q) = %v, want an error", tc.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v", tc.value, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "1024", want: 1024},
+		{value: "500MB", want: 500 * 1000 * 1000},
+		{value: "1.5 GB", want: 1500 * 1000 * 1000},
+		{value: "512MiB", want: 512 << 20},
+		{value: "10kb", want: 10 * 1000},
+		{value: "1KiB", want: 1 << 10},
+		{value: "-1MB", wantErr: true},
+		{value: "10 parsecs", wantErr: true},
+		{value: "MB", wantErr: true},
+	} {
+		got, err := parseSize(tc.value)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseSize(%q) = %d, want an error", tc.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tc.value, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// lockRefPrefix is the namespace of lock refs. They are hidden from the
+// repository's branches and tags.
+const lockRefPrefix = "refs/livekit-deploy-locks/"
+
+const (
+	lockHeldMessage     = "LiveKit deploy lock held by "
+	lockReleasedMessage = "LiveKit deploy lock released"
+)
+
+var (
+	errLockHeld         = errors.New("deploy lock is held by another run")
+	errLockRace         = errors.New("deploy lock changed concurrently")
+	lockKeyInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// deployLock serializes the runs that change an agent. It is a ref in the
+// repository pointing to a commit whose message records the holder, or that
+// the lock was released, and whose date records when. Every change to the lock
+// is a new commit on top of the one it replaces, pushed as a fast-forward, so
+// GitHub rejects it when another run changed the lock first. Acquiring, taking
+// over a stale lock left by a run that was killed, and releasing are therefore
+// all atomic, and a run never releases a lock another run took over.
+type deployLock struct {
+	client *githubClient
+	ref    string
+	holder string
+	// tree is the tree of the lock commits, the tree of the current commit
+	tree string
+	// held is the commit this run holds the lock with, "" when not held
+	held string
+}
+
+// lockState is the commit the lock ref points to.
+type lockState struct {
+	SHA        string
+	Released   bool
+	Holder     string
+	AcquiredAt time.Time
+}
+
+func newDeployLock(client *githubClient, key string) *deployLock {
+	holder := fmt.Sprintf("%s run %s attempt %s", os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"), os.Getenv("GITHUB_RUN_ATTEMPT"))
+	return &deployLock{
+		client: client,
+		ref:    lockRefPrefix + lockKeyInvalidChars.ReplaceAllString(key, "-"),
+		holder: holder,
+	}
+}
+
+// acquire takes the lock, waiting for it to be released until timeout when
+// wait is set. A lock held for longer than staleAfter is taken over.
+func (l *deployLock) acquire(ctx context.Context, wait bool, timeout time.Duration, interval time.Duration, staleAfter time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := l.current(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case state == nil:
+			err = l.create(ctx)
+		case state.Released:
+			err = l.swap(ctx, state.SHA, lockHeldMessage+l.holder)
+		case staleAfter > 0 && time.Since(state.AcquiredAt) > staleAfter:
+			log.Warnw("Taking over stale deploy lock", nil, "holder", state.Holder, "acquiredAt", state.AcquiredAt)
+			err = l.swap(ctx, state.SHA, lockHeldMessage+l.holder)
+		default:
+			if !wait {
+				return fmt.Errorf("%w: %s", errLockHeld, state.Holder)
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("timed out waiting for deploy lock held by %s", state.Holder)
+			}
+			log.Infow("Waiting for deploy lock", "holder", state.Holder, "acquiredAt", state.AcquiredAt)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+			continue
+		}
+
+		// another run changed the lock in the meantime, look at it again
+		if errors.Is(err, errLockRace) {
+			continue
+		}
+		return err
+	}
+}
+
+// create creates the lock ref, when no run used the lock yet.
+func (l *deployLock) create(ctx context.Context) error {
+	sha, err := l.commit(ctx, lockHeldMessage+l.holder, "")
+	if err != nil {
+		return err
+	}
+	err = l.client.do(ctx, http.MethodPost, "/git/refs", map[string]string{
+		"ref": l.ref,
+		"sha": sha,
+	}, nil)
+	if isGitHubStatus(err, http.StatusUnprocessableEntity) {
+		return errLockRace
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create deploy lock: %w", err)
+	}
+	l.held = sha
+	return nil
+}
+
+// swap moves the lock from the commit parent to a new commit with message. It
+// fails with errLockRace when the lock no longer points to parent.
+func (l *deployLock) swap(ctx context.Context, parent string, message string) error {
+	sha, err := l.commit(ctx, message, parent)
+	if err != nil {
+		return err
+	}
+	// without force, the update must be a fast-forward from the current commit
+	err = l.client.do(ctx, http.MethodPatch, "/git/refs/"+l.ref[len("refs/"):], map[string]any{
+		"sha":   sha,
+		"force": false,
+	}, nil)
+	if isGitHubStatus(err, http.StatusUnprocessableEntity) {
+		return errLockRace
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update deploy lock: %w", err)
+	}
+	if strings.HasPrefix(message, lockHeldMessage) {
+		l.held = sha
+	} else {
+		l.held = ""
+	}
+	return nil
+}
+
+// commit creates a lock commit on top of parent, or without a parent when it
+// is "".
+func (l *deployLock) commit(ctx context.Context, message string, parent string) (string, error) {
+	if l.tree == "" {
+		sha := commitSHA()
+		if sha == "" {
+			return "", fmt.Errorf("GITHUB_SHA is not set")
+		}
+		var head struct {
+			Tree struct {
+				SHA string `json:"sha"`
+			} `json:"tree"`
+		}
+		if err := l.client.do(ctx, http.MethodGet, "/git/commits/"+sha, nil, &head); err != nil {
+			return "", fmt.Errorf("failed to get commit %s: %w", sha, err)
+		}
+		l.tree = head.Tree.SHA
+	}
+
+	parents := []string{}
+	if parent != "" {
+		parents = append(parents, parent)
+	}
+	signature := map[string]string{
+		"name":  "LiveKit Cloud deploy action",
+		"email": "noreply@livekit.io",
+		"date":  time.Now().UTC().Format(time.RFC3339),
+	}
+	var created struct {
+		SHA string `json:"sha"`
+	}
+	if err := l.client.do(ctx, http.MethodPost, "/git/commits", map[string]any{
+		"message":   message,
+		"tree":      l.tree,
+		"parents":   parents,
+		"author":    signature,
+		"committer": signature,
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to create deploy lock commit: %w", err)
+	}
+	return created.SHA, nil
+}
+
+// current returns the state of the lock, or nil when no run used it yet.
+func (l *deployLock) current(ctx context.Context) (*lockState, error) {
+	var ref struct {
+		Object struct {
+			SHA  string `json:"sha"`
+			Type string `json:"type"`
+		} `json:"object"`
+	}
+	err := l.client.do(ctx, http.MethodGet, "/git/ref/"+l.ref[len("refs/"):], nil, &ref)
+	if isGitHubStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deploy lock: %w", err)
+	}
+	// locks of earlier versions of the action point to tags, and anything
+	// else was not made by the action
+	if ref.Object.Type != "commit" {
+		return nil, fmt.Errorf("deploy lock %s points to a %s rather than a lock commit, delete the ref to continue", l.ref, ref.Object.Type)
+	}
+
+	var commit struct {
+		Message   string `json:"message"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	}
+	if err := l.client.do(ctx, http.MethodGet, "/git/commits/"+ref.Object.SHA, nil, &commit); err != nil {
+		return nil, fmt.Errorf("failed to get deploy lock: %w", err)
+	}
+	return parseLockCommit(ref.Object.SHA, commit.Message, commit.Committer.Date)
+}
+
+// parseLockCommit returns the lock state recorded by a lock commit.
+func parseLockCommit(sha string, message string, date time.Time) (*lockState, error) {
+	message = strings.TrimSpace(message)
+	if message == lockReleasedMessage {
+		return &lockState{SHA: sha, Released: true}, nil
+	}
+	holder, ok := strings.CutPrefix(message, lockHeldMessage)
+	if !ok {
+		return nil, fmt.Errorf("deploy lock commit %s was not made by the action, delete the ref to continue", sha)
+	}
+	return &lockState{SHA: sha, Holder: holder, AcquiredAt: date}, nil
+}
+
+// release releases the lock if this run still holds it.
+func (l *deployLock) release(ctx context.Context) error {
+	if l.held == "" {
+		return nil
+	}
+	err := l.swap(ctx, l.held, lockReleasedMessage)
+	if errors.Is(err, errLockRace) {
+		log.Warnw("Deploy lock was taken over by another run, leaving it in place", nil, "lock", l.ref)
+		l.held = ""
+		return nil
+	}
+	return err
+}
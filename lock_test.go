@@ -0,0 +1,270 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeCommit struct {
+	message string
+	date    time.Time
+	parents []string
+}
+
+// fakeGitRefs serves the parts of the GitHub git database API the deploy
+// lock uses, rejecting ref updates that are not fast-forwards as GitHub does.
+type fakeGitRefs struct {
+	mu      sync.Mutex
+	refs    map[string]string
+	types   map[string]string
+	commits map[string]fakeCommit
+}
+
+func newFakeGitRefs(t *testing.T) (*fakeGitRefs, *githubClient) {
+	f := &fakeGitRefs{refs: make(map[string]string), types: make(map[string]string), commits: make(map[string]fakeCommit)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	t.Setenv("GITHUB_SHA", "head")
+	t.Setenv("GITHUB_EVENT_PATH", "")
+	return f, &githubClient{token: "token", baseURL: server.URL, repo: "owner/repo"}
+}
+
+func (f *fakeGitRefs) addCommit(message string, date time.Time, parents ...string) string {
+	sha := fmt.Sprintf("c%d", len(f.commits)+1)
+	f.commits[sha] = fakeCommit{message: message, date: date, parents: parents}
+	return sha
+}
+
+func (f *fakeGitRefs) isAncestor(ancestor string, sha string) bool {
+	if sha == ancestor {
+		return true
+	}
+	for _, parent := range f.commits[sha].parents {
+		if f.isAncestor(ancestor, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeGitRefs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo")
+	var body struct {
+		Ref       string   `json:"ref"`
+		SHA       string   `json:"sha"`
+		Force     bool     `json:"force"`
+		Message   string   `json:"message"`
+		Parents   []string `json:"parents"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	reply := func(v any) {
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "/git/commits/head":
+		reply(map[string]any{"tree": map[string]string{"sha": "tree"}})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/commits/"):
+		commit, ok := f.commits[strings.TrimPrefix(path, "/git/commits/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		reply(map[string]any{"message": commit.message, "committer": map[string]any{"date": commit.date}})
+	case r.Method == http.MethodPost && path == "/git/commits":
+		reply(map[string]string{"sha": f.addCommit(body.Message, body.Committer.Date, body.Parents...)})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/git/ref/"):
+		ref := "refs/" + strings.TrimPrefix(path, "/git/ref/")
+		sha, ok := f.refs[ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		typ := f.types[ref]
+		if typ == "" {
+			typ = "commit"
+		}
+		reply(map[string]any{"object": map[string]string{"sha": sha, "type": typ}})
+	case r.Method == http.MethodPost && path == "/git/refs":
+		if _, ok := f.refs[body.Ref]; ok {
+			http.Error(w, "Reference already exists", http.StatusUnprocessableEntity)
+			return
+		}
+		f.refs[body.Ref] = body.SHA
+		reply(map[string]string{})
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/git/refs/"):
+		ref := "refs/" + strings.TrimPrefix(path, "/git/refs/")
+		current, ok := f.refs[ref]
+		if !ok {
+			http.Error(w, "Reference does not exist", http.StatusUnprocessableEntity)
+			return
+		}
+		if !body.Force && !f.isAncestor(current, body.SHA) {
+			http.Error(w, "Update is not a fast forward", http.StatusUnprocessableEntity)
+			return
+		}
+		f.refs[ref] = body.SHA
+		reply(map[string]string{})
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestDeployLockAcquireAndRelease(t *testing.T) {
+	_, client := newFakeGitRefs(t)
+	ctx := context.Background()
+
+	a := newDeployLock(client, "agent")
+	a.holder = "run a"
+	b := newDeployLock(client, "agent")
+	b.holder = "run b"
+
+	if err := a.acquire(ctx, false, time.Second, time.Millisecond, time.Hour); err != nil {
+		t.Fatalf("acquiring a free lock: %v", err)
+	}
+	err := b.acquire(ctx, false, time.Second, time.Millisecond, time.Hour)
+	if !errors.Is(err, errLockHeld) || !strings.Contains(err.Error(), "run a") {
+		t.Fatalf("acquiring a held lock: got %v, want it held by run a", err)
+	}
+	if err := b.acquire(ctx, true, 10*time.Millisecond, time.Millisecond, time.Hour); err == nil {
+		t.Fatal("waiting for a held lock did not time out")
+	}
+
+	if err := a.release(ctx); err != nil {
+		t.Fatalf("releasing: %v", err)
+	}
+	if err := b.acquire(ctx, false, time.Second, time.Millisecond, time.Hour); err != nil {
+		t.Fatalf("acquiring a released lock: %v", err)
+	}
+	state, err := b.current(ctx)
+	if err != nil || state == nil || state.Holder != "run b" {
+		t.Fatalf("got state %+v, %v, want held by run b", state, err)
+	}
+}
+
+func TestDeployLockStaleTakeover(t *testing.T) {
+	refs, client := newFakeGitRefs(t)
+	ctx := context.Background()
+
+	a := newDeployLock(client, "agent")
+	a.holder = "run a"
+	if err := a.acquire(ctx, false, time.Second, time.Millisecond, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// age the lock past staleAfter
+	held := refs.commits[a.held]
+	held.date = time.Now().Add(-2 * time.Hour)
+	refs.commits[a.held] = held
+
+	b := newDeployLock(client, "agent")
+	b.holder = "run b"
+	if err := b.acquire(ctx, false, time.Second, time.Millisecond, time.Hour); err != nil {
+		t.Fatalf("taking over a stale lock: %v", err)
+	}
+
+	// the run whose lock was taken over must leave the new holder's lock
+	if err := a.release(ctx); err != nil {
+		t.Fatalf("releasing a lock that was taken over: %v", err)
+	}
+	state, err := b.current(ctx)
+	if err != nil || state == nil || state.Released || state.Holder != "run b" {
+		t.Fatalf("got state %+v, %v, want still held by run b", state, err)
+	}
+}
+
+func TestDeployLockSwapIsConditional(t *testing.T) {
+	_, client := newFakeGitRefs(t)
+	ctx := context.Background()
+
+	a := newDeployLock(client, "agent")
+	a.holder = "run a"
+	if err := a.acquire(ctx, false, time.Second, time.Millisecond, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	stale := a.held
+
+	// two runs that both saw the same stale lock, only the first gets it
+	b := newDeployLock(client, "agent")
+	c := newDeployLock(client, "agent")
+	if err := b.swap(ctx, stale, lockHeldMessage+"run b"); err != nil {
+		t.Fatalf("first takeover: %v", err)
+	}
+	if err := c.swap(ctx, stale, lockHeldMessage+"run c"); !errors.Is(err, errLockRace) {
+		t.Fatalf("second takeover: got %v, want %v", err, errLockRace)
+	}
+	if c.held != "" {
+		t.Fatal("the losing run thinks it holds the lock")
+	}
+}
+
+func TestDeployLockForeignRef(t *testing.T) {
+	refs, client := newFakeGitRefs(t)
+	ctx := context.Background()
+
+	l := newDeployLock(client, "agent")
+	refs.refs[l.ref] = "tag"
+	refs.types[l.ref] = "tag"
+	if err := l.acquire(ctx, true, time.Second, time.Millisecond, time.Hour); err == nil || !strings.Contains(err.Error(), "delete the ref") {
+		t.Fatalf("got %v, want an error asking to delete the ref", err)
+	}
+
+	refs.types[l.ref] = ""
+	refs.refs[l.ref] = refs.addCommit("not a lock", time.Now())
+	if err := l.acquire(ctx, true, time.Second, time.Millisecond, time.Hour); err == nil {
+		t.Fatal("acquired a lock pointing to a commit not made by the action")
+	}
+}
+
+func TestParseLockCommit(t *testing.T) {
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		message string
+		want    *lockState
+		wantErr bool
+	}{
+		{message: lockReleasedMessage + "\n", want: &lockState{SHA: "sha", Released: true}},
+		{message: lockHeldMessage + "owner/repo run 1 attempt 2", want: &lockState{SHA: "sha", Holder: "owner/repo run 1 attempt 2", AcquiredAt: date}},
+		{message: "Initial commit", wantErr: true},
+	} {
+		got, err := parseLockCommit("sha", tc.message, date)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseLockCommit(%q) = %+v, want an error", tc.message, got)
+			}
+			continue
+		}
+		if err != nil || *got != *tc.want {
+			t.Errorf("parseLockCommit(%q) = %+v, %v, want %+v", tc.message, got, err, tc.want)
+		}
+	}
+}
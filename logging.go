@@ -0,0 +1,198 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// shorter values would garble unrelated output
+	minScrubLength = 4
+)
+
+// scrubber holds the secret values to redact from log output.
+type scrubber struct {
+	mu       sync.RWMutex
+	pairs    []string
+	replacer *strings.Replacer
+}
+
+var logScrubber = &scrubber{}
+
+// add registers values to be redacted from every later log entry.
+func (s *scrubber) add(values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) < minScrubLength {
+			continue
+		}
+		s.pairs = append(s.pairs, value, redacted)
+	}
+	if len(s.pairs) > 0 {
+		s.replacer = strings.NewReplacer(s.pairs...)
+	}
+}
+
+func (s *scrubber) scrub(str string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.replacer == nil {
+		return str
+	}
+	return s.replacer.Replace(str)
+}
+
+// scrubValue redacts secrets from a log field, keeping its type when it
+// contains none.
+func (s *scrubber) scrubValue(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return s.scrub(v)
+	case error:
+		if msg := v.Error(); s.scrub(msg) != msg {
+			return errors.New(s.scrub(msg))
+		}
+		return v
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v
+	default:
+		if str := fmt.Sprint(v); s.scrub(str) != str {
+			return s.scrub(str)
+		}
+		return v
+	}
+}
+
+func (s *scrubber) scrubValues(keysAndValues []any) []any {
+	scrubbed := make([]any, len(keysAndValues))
+	for i, value := range keysAndValues {
+		scrubbed[i] = s.scrubValue(value)
+	}
+	return scrubbed
+}
+
+func (s *scrubber) scrubError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return s.scrubValue(err).(error)
+}
+
+var (
+	loggedMu sync.Mutex
+	// warnings are the messages of every warning logged, for the result output
+	warnings []string
+	// lastError is the last error logged, which usually ended the operation
+	lastError string
+)
+
+func loggedMessage(msg string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%s: %v", msg, err)
+	}
+	return msg
+}
+
+func recordWarning(msg string, err error) {
+	loggedMu.Lock()
+	defer loggedMu.Unlock()
+	warnings = append(warnings, loggedMessage(msg, err))
+}
+
+func recordError(msg string, err error) {
+	loggedMu.Lock()
+	defer loggedMu.Unlock()
+	lastError = loggedMessage(msg, err)
+}
+
+// scrubbingLogger redacts registered secret values from every message, error
+// and field before handing the entry to the wrapped logger.
+type scrubbingLogger struct {
+	logger.Logger
+	s *scrubber
+}
+
+func newScrubbingLogger(l logger.Logger, s *scrubber) logger.Logger {
+	// account for the extra frame so callers are reported correctly
+	return &scrubbingLogger{Logger: l.WithCallDepth(1), s: s}
+}
+
+func (l *scrubbingLogger) wrap(inner logger.Logger) logger.Logger {
+	return &scrubbingLogger{Logger: inner, s: l.s}
+}
+
+func (l *scrubbingLogger) Debugw(msg string, keysAndValues ...any) {
+	l.Logger.Debugw(l.s.scrub(msg), l.s.scrubValues(keysAndValues)...)
+}
+
+func (l *scrubbingLogger) Infow(msg string, keysAndValues ...any) {
+	l.Logger.Infow(l.s.scrub(msg), l.s.scrubValues(keysAndValues)...)
+}
+
+func (l *scrubbingLogger) Warnw(msg string, err error, keysAndValues ...any) {
+	err = l.s.scrubError(err)
+	l.Logger.Warnw(l.s.scrub(msg), err, l.s.scrubValues(keysAndValues)...)
+	recordWarning(l.s.scrub(msg), err)
+}
+
+func (l *scrubbingLogger) Errorw(msg string, err error, keysAndValues ...any) {
+	err = l.s.scrubError(err)
+	l.Logger.Errorw(l.s.scrub(msg), err, l.s.scrubValues(keysAndValues)...)
+	recordError(l.s.scrub(msg), err)
+}
+
+func (l *scrubbingLogger) WithValues(keysAndValues ...any) logger.Logger {
+	return l.wrap(l.Logger.WithValues(l.s.scrubValues(keysAndValues)...))
+}
+
+func (l *scrubbingLogger) WithName(name string) logger.Logger {
+	return l.wrap(l.Logger.WithName(name))
+}
+
+func (l *scrubbingLogger) WithComponent(component string) logger.Logger {
+	return l.wrap(l.Logger.WithComponent(component))
+}
+
+func (l *scrubbingLogger) WithCallDepth(depth int) logger.Logger {
+	return l.wrap(l.Logger.WithCallDepth(depth))
+}
+
+func (l *scrubbingLogger) WithItemSampler() logger.Logger {
+	return l.wrap(l.Logger.WithItemSampler())
+}
+
+func (l *scrubbingLogger) WithoutSampler() logger.Logger {
+	return l.wrap(l.Logger.WithoutSampler())
+}
+
+func (l *scrubbingLogger) WithUnlikelyValues(keysAndValues ...any) logger.UnlikelyLogger {
+	return logger.NewUnlikelyLogger(l, keysAndValues...)
+}
+
+func (l *scrubbingLogger) WithDeferredValues() (logger.Logger, logger.DeferredFieldResolver) {
+	inner, resolver := l.Logger.WithDeferredValues()
+	return l.wrap(inner), resolver
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+// tailWriter buffers written lines, keeping only the last n of them.
+// A limit of 0 keeps every line. It is safe for concurrent use, writes after
+// close fail.
+type tailWriter struct {
+	mu      sync.Mutex
+	limit   int
+	lines   []string
+	partial bytes.Buffer
+	closed  bool
+}
+
+func newTailWriter(limit int) *tailWriter {
+	return &tailWriter{limit: limit}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	w.partial.Write(p)
+	for {
+		line, err := w.partial.ReadString('\n')
+		if err != nil {
+			// incomplete line, keep it for the next write
+			w.partial.Reset()
+			w.partial.WriteString(line)
+			break
+		}
+		w.append(strings.TrimSuffix(line, "\n"))
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) append(line string) {
+	w.lines = append(w.lines, line)
+	if w.limit > 0 && len(w.lines) > w.limit {
+		w.lines = w.lines[len(w.lines)-w.limit:]
+	}
+}
+
+// Close stops the writer from accepting more lines.
+func (w *tailWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+// Lines returns a copy of the buffered lines, including any trailing partial
+// line.
+func (w *tailWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.partial.Len() > 0 {
+		w.append(w.partial.String())
+		w.partial.Reset()
+	}
+	return append([]string(nil), w.lines...)
+}
+
+func (w *tailWriter) WriteTo(out io.Writer) (int64, error) {
+	var total int64
+	for _, line := range w.Lines() {
+		n, err := io.WriteString(out, line+"\n")
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// streamLogs streams the agent's logs into w until the stream ends or ctx is
+// done. The SDK doesn't attach ctx to the request and only checks it between
+// lines, so a quiet stream would block past the deadline; the stream is read
+// in the background instead and w is closed once ctx is done, returning
+// ctx.Err() with whatever was collected.
+func streamLogs(ctx context.Context, client *cloudagents.Client, logType, agentID, region string, w *tailWriter) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- client.StreamLogs(ctx, logType, agentID, w, region)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		w.Close()
+		return ctx.Err()
+	}
+}
+
+// fetchRuntimeLog fetches the last lines of the agent's runtime log, with
+// secret values scrubbed. Runtime logs are streamed until the connection is
+// closed, so the request is bounded and whatever was collected is returned.
+func fetchRuntimeLog(client *cloudagents.Client, agentID string, lines int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w := newTailWriter(lines)
+	err := streamLogs(ctx, client, "deploy", agentID, "", w)
+	tail := w.Lines()
+	for i, line := range tail {
+		tail[i] = logScrubber.scrub(line)
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return tail, err
+	}
+	return tail, nil
+}
+
+var (
+	buildOutputMu sync.Mutex
+	// stopBuildOutput restores stderr after streamBuildOutput, nil when the
+	// build output is not being streamed.
+	stopBuildOutput func()
+)
+
+// streamBuildOutput forwards the build progress the SDK writes to stderr to
+// the workflow log line by line as it arrives, scrubbing secret values from
+// it. endBuildOutput must be called once the build returns.
+func streamBuildOutput() {
+	r, w, err := os.Pipe()
+	if err != nil {
+		log.Warnw("Failed to stream build output, it is written unscrubbed", err)
+		return
+	}
+
+	buildOutputMu.Lock()
+	defer buildOutputMu.Unlock()
+	stderr := os.Stderr
+	os.Stderr = w
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				io.WriteString(stderr, logScrubber.scrub(line))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	stopBuildOutput = func() {
+		os.Stderr = stderr
+		w.Close()
+		<-done
+		r.Close()
+	}
+}
+
+// endBuildOutput flushes the streamed build output and restores stderr. It
+// does nothing when the output is not being streamed.
+func endBuildOutput() {
+	buildOutputMu.Lock()
+	defer buildOutputMu.Unlock()
+	if stopBuildOutput != nil {
+		stopBuildOutput()
+		stopBuildOutput = nil
+	}
+}
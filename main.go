@@ -15,17 +15,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/BurntSushi/toml"
+
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
-
-	"github.com/slack-go/slack"
 )
 
 var (
@@ -38,14 +50,19 @@ func main() {
 		JSON:  true,
 		Level: "debug",
 	})
-	log = zl.WithValues()
+	log = newScrubbingLogger(zl.WithValues(), logScrubber)
 	logger.SetLogger(log, "cloud-agents-github-plugin")
 
+	handleCancellation()
+
+	startTime := time.Now()
+	result.StartedAt = startTime.UTC()
 	operation := os.Getenv("INPUT_OPERATION")
 	if operation == "" {
 		log.Errorw("OPERATION is not set", nil)
-		os.Exit(1)
+		exit(1)
 	}
+	result.Operation = operation
 
 	region := os.Getenv("INPUT_REGION")
 	if region == "" {
@@ -58,83 +75,333 @@ func main() {
 	}
 	log.Infow("Running in", "path", workingDir)
 
-	agentIds := strings.Split(os.Getenv("INPUT_AGENT_IDS"), ",")
+	outputPrefix = os.Getenv("INPUT_OUTPUT_PREFIX")
+	if outputPrefix != "" && !outputPrefixPattern.MatchString(outputPrefix) {
+		log.Errorw("Invalid OUTPUT_PREFIX, expected letters, digits, - and _", nil, "value", outputPrefix)
+		exit(1)
+	}
+
+	loadNotificationTemplate()
+	for _, id := range getListInput("SLACK_MENTIONS") {
+		if !slackMentionPattern.MatchString(id) {
+			log.Errorw("Invalid SLACK_MENTIONS, expected user or user group IDs, here or channel", nil, "value", id)
+			exit(1)
+		}
+	}
+	loadNotifierConfig()
+
+	// validate only inspects livekit.toml and does not need credentials
+	if operation == "validate" {
+		validateConfig(workingDir)
+		exit(0)
+	}
+
+	agentIds := getListInput("AGENT_IDS")
 	if len(agentIds) > 0 {
 		log.Infow("Using agent IDs from INPUT_AGENT_IDS", "agentIds", agentIds)
 	}
 
-	timeout := os.Getenv("INPUT_TIMEOUT")
-	if timeout == "" {
-		timeout = "5m"
+	version := os.Getenv("INPUT_AGENT_VERSION")
+
+	timeoutDuration := getDurationInput("TIMEOUT", 5*time.Minute)
+	intervalDuration := getDurationInput("INTERVAL", 5*time.Second)
+	if intervalDuration <= 0 {
+		log.Errorw("Invalid INTERVAL, expected a duration greater than zero", nil, "value", os.Getenv("INPUT_INTERVAL"))
+		exit(1)
+	}
+
+	logType := os.Getenv("INPUT_LOG_TYPE")
+	if logType == "" {
+		logType = "deploy"
+	}
+	logTail := getIntInput("LOG_TAIL")
+
+	maxDeployAge := getDurationInput("HEALTH_MAX_DEPLOY_AGE", 0)
+
+	secretsMode := os.Getenv("INPUT_SECRETS_MODE")
+	switch secretsMode {
+	case "":
+		secretsMode = "replace"
+	case "replace", "merge", "sync":
+	default:
+		log.Errorw("Invalid SECRETS_MODE, expected replace, merge or sync", nil, "value", secretsMode)
+		exit(1)
+	}
+
+	startGroup("Load secrets")
+	// get all the env vars that are prefixed with SECRET_, or the configured prefixes
+	secretPrefixes := getListInput("SECRET_PREFIX")
+	if len(secretPrefixes) == 0 {
+		secretPrefixes = []string{"SECRET_"}
 	}
-	timeoutDuration, err := time.ParseDuration(timeout)
+	envSecrets, err := loadEnvSecrets(secretPrefixes)
 	if err != nil {
-		log.Errorw("Invalid timeout", err)
-		os.Exit(1)
+		log.Errorw("Failed to load secrets", err)
+		exit(1)
+	}
+	// the credential inputs take precedence, then the SECRET_ prefixed vars,
+	// which are also forwarded to the agent, then the plain env vars
+	lkUrl = strings.TrimSpace(os.Getenv("INPUT_LIVEKIT_URL"))
+	lkApiKey = strings.TrimSpace(os.Getenv("INPUT_LIVEKIT_API_KEY"))
+	lkApiSecret = strings.TrimSpace(os.Getenv("INPUT_LIVEKIT_API_SECRET"))
+	for _, secret := range envSecrets {
+		switch {
+		case secret.Name == "LIVEKIT_URL" && lkUrl == "":
+			lkUrl = string(secret.Value)
+		case secret.Name == "LIVEKIT_API_KEY" && lkApiKey == "":
+			lkApiKey = string(secret.Value)
+		case secret.Name == "LIVEKIT_API_SECRET" && lkApiSecret == "":
+			lkApiSecret = string(secret.Value)
+		}
+	}
+	if lkUrl == "" {
+		lkUrl = getInputOrEnv("LIVEKIT_URL")
+	}
+	if lkApiKey == "" {
+		lkApiKey = getInputOrEnv("LIVEKIT_API_KEY")
+	}
+	if lkApiSecret == "" {
+		lkApiSecret = getInputOrEnv("LIVEKIT_API_SECRET")
+	}
+
+	if lkUrl == "" || lkApiKey == "" || lkApiSecret == "" {
+		log.Errorw("LIVEKIT_URL, LIVEKIT_API_KEY, and LIVEKIT_API_SECRET must be set", nil)
+		exit(1)
+	}
+	if u, err := url.Parse(lkUrl); err != nil || !slices.Contains([]string{"ws", "wss", "http", "https"}, u.Scheme) || u.Host == "" {
+		log.Errorw("Invalid LIVEKIT_URL, expected a wss:// or https:// URL", err, "value", lkUrl)
+		exit(1)
 	}
 
-	// get all the env vars that are prefixed with SECRET_
-	secrets := make([]*livekit.AgentSecret, 0)
-	for _, env := range os.Environ() {
-		if strings.HasPrefix(env, "SECRET_") {
-			// ignore the SECRET_LIST env var
-			if strings.HasPrefix(env, "SECRET_LIST=") {
+	// only forward the env secrets matching SECRET_INCLUDE and not SECRET_EXCLUDE
+	secretInclude, secretExclude := getListInput("SECRET_INCLUDE"), getListInput("SECRET_EXCLUDE")
+	if len(secretInclude) > 0 || len(secretExclude) > 0 {
+		filtered := envSecrets[:0]
+		for _, secret := range envSecrets {
+			include, err := matchSecretName(secret.Name, secretInclude, secretExclude)
+			if err != nil {
+				log.Errorw("Invalid SECRET_INCLUDE or SECRET_EXCLUDE", err)
+				exit(1)
+			}
+			if !include {
+				log.Infow("Not forwarding filtered secret", "secret", secret.Name)
 				continue
 			}
+			filtered = append(filtered, secret)
+		}
+		envSecrets = filtered
+	}
 
-			secretParts := strings.SplitN(strings.TrimPrefix(env, "SECRET_"), "=", 2)
-			secretName := secretParts[0]
-			secretValue := strings.TrimSpace(secretParts[1])
-
-			log.Infow("Loading secret", "secret", secretName)
-			if secretName == "LIVEKIT_URL" || secretName == "LIVEKIT_API_KEY" || secretName == "LIVEKIT_API_SECRET" {
-				switch secretName {
-				case "LIVEKIT_URL":
-					lkUrl = secretValue
-				case "LIVEKIT_API_KEY":
-					lkApiKey = secretValue
-				case "LIVEKIT_API_SECRET":
-					lkApiSecret = secretValue
-				}
+	// the LiveKit credentials can be kept for API auth only, for agents that
+	// use their own, more narrowly scoped key
+	if getBoolInput("EXCLUDE_LIVEKIT_CREDENTIALS") {
+		envSecrets = slices.DeleteFunc(envSecrets, func(secret *livekit.AgentSecret) bool {
+			switch secret.Name {
+			case "LIVEKIT_URL", "LIVEKIT_API_KEY", "LIVEKIT_API_SECRET":
+				log.Infow("Not forwarding LiveKit credential to the agent", "secret", secret.Name)
+				return true
 			}
-			secrets = append(secrets, &livekit.AgentSecret{
-				Name:  secretName,
-				Value: []byte(secretValue),
-			})
+			return false
+		})
+	}
+
+	sources := []secretSource{{Name: "env", Secrets: envSecrets}}
+
+	// some use cases require a list of secrets to be passed in as a comma separated list of SECRET_NAME=SECRET_VALUE
+	if secretList := getInputOrEnv("SECRET_LIST"); secretList != "" {
+		listSecrets, err := parseSecretList(secretList)
+		if err != nil {
+			log.Errorw("Failed to load SECRET_LIST", err)
+			exit(1)
 		}
+		sources = append(sources, secretSource{Name: "SECRET_LIST", Secrets: listSecrets})
 	}
 
-	if lkUrl == "" || lkApiKey == "" || lkApiSecret == "" {
-		// try to load directly from the env first instead of the SECRET_ prefix
-		lkUrl = strings.TrimSpace(os.Getenv("LIVEKIT_URL"))
-		lkApiKey = strings.TrimSpace(os.Getenv("LIVEKIT_API_KEY"))
-		lkApiSecret = strings.TrimSpace(os.Getenv("LIVEKIT_API_SECRET"))
+	// SECRETS_JSON carries values losslessly, including commas, equals signs and newlines
+	if secretsJSON := os.Getenv("INPUT_SECRETS_JSON"); secretsJSON != "" {
+		jsonSecrets, err := parseSecretsJSON(secretsJSON)
+		if err != nil {
+			log.Errorw("Failed to load SECRETS_JSON", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "SECRETS_JSON", Secrets: jsonSecrets})
+	}
 
-		if lkUrl == "" || lkApiKey == "" || lkApiSecret == "" {
-			log.Errorw("LIVEKIT_URL, LIVEKIT_API_KEY, and LIVEKIT_API_SECRET must be set", nil)
-			os.Exit(1)
+	if secretsFile := os.Getenv("INPUT_SECRETS_FILE"); secretsFile != "" {
+		fileSecrets, err := loadSecretsFile(secretsFile)
+		if err != nil {
+			log.Errorw("Failed to load SECRETS_FILE", err)
+			exit(1)
 		}
+		sources = append(sources, secretSource{Name: "SECRETS_FILE", Secrets: fileSecrets})
 	}
 
-	// some use cases require a list of secrets to be passed in as a comma separated list of SECRET_NAME=SECRET_VALUE
-	if os.Getenv("SECRET_LIST") != "" {
-		secretList := strings.Split(os.Getenv("SECRET_LIST"), ",")
-		for _, secret := range secretList {
-			secretParts := strings.SplitN(secret, "=", 2)
-			if len(secretParts) != 2 {
-				log.Errorw("Invalid secret format", nil, "secret", secret)
-				os.Exit(1)
+	if sopsFile := os.Getenv("INPUT_SOPS_FILE"); sopsFile != "" {
+		sopsSecrets, err := loadSopsFile(sopsFile)
+		if err != nil {
+			log.Errorw("Failed to load SOPS_FILE", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "SOPS_FILE", Secrets: sopsSecrets})
+	}
+
+	if ageFile := os.Getenv("INPUT_AGE_FILE"); ageFile != "" {
+		ageSecrets, err := loadAgeBundle(ageFile, os.Getenv("INPUT_AGE_KEY"))
+		if err != nil {
+			log.Errorw("Failed to load AGE_FILE", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "AGE_FILE", Secrets: ageSecrets})
+	}
+
+	if vaultPaths := getListInput("VAULT_PATHS"); len(vaultPaths) > 0 {
+		vaultSecrets, err := loadVaultSecrets(context.Background(), vaultConfig{
+			Address:   os.Getenv("INPUT_VAULT_ADDR"),
+			Namespace: os.Getenv("INPUT_VAULT_NAMESPACE"),
+			Token:     os.Getenv("INPUT_VAULT_TOKEN"),
+			Role:      os.Getenv("INPUT_VAULT_ROLE"),
+			AuthPath:  os.Getenv("INPUT_VAULT_AUTH_PATH"),
+			Paths:     vaultPaths,
+		})
+		if err != nil {
+			log.Errorw("Failed to load secrets from Vault", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "VAULT", Secrets: vaultSecrets})
+	}
+
+	awsSecretIDs, awsParameters := getListInput("AWS_SECRET_IDS"), getListInput("AWS_SSM_PARAMETERS")
+	if len(awsSecretIDs) > 0 || len(awsParameters) > 0 {
+		awsSecrets, err := loadAWSSecrets(context.Background(), awsSecretsConfig{
+			SecretIDs:  awsSecretIDs,
+			Parameters: awsParameters,
+			ExpandJSON: getBoolInput("AWS_EXPAND_JSON"),
+		})
+		if err != nil {
+			log.Errorw("Failed to load secrets from AWS", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "AWS", Secrets: awsSecrets})
+	}
+
+	if gcpSecrets := getListInput("GCP_SECRETS"); len(gcpSecrets) > 0 {
+		loaded, err := loadGCPSecrets(context.Background(), gcpSecrets)
+		if err != nil {
+			log.Errorw("Failed to load secrets from Google Secret Manager", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "GCP", Secrets: loaded})
+	}
+
+	if azureSecrets := getListInput("AZURE_KEYVAULT_SECRETS"); len(azureSecrets) > 0 {
+		loaded, err := loadAzureSecrets(context.Background(), os.Getenv("INPUT_AZURE_KEYVAULT_URL"), azureSecrets)
+		if err != nil {
+			log.Errorw("Failed to load secrets from Azure Key Vault", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "AZURE", Secrets: loaded})
+	}
+
+	if dopplerToken := os.Getenv("INPUT_DOPPLER_TOKEN"); dopplerToken != "" {
+		loaded, err := loadDopplerSecrets(context.Background(), dopplerConfig{
+			Token:   dopplerToken,
+			Project: os.Getenv("INPUT_DOPPLER_PROJECT"),
+			Config:  os.Getenv("INPUT_DOPPLER_CONFIG"),
+			Include: getListInput("DOPPLER_INCLUDE"),
+			Exclude: getListInput("DOPPLER_EXCLUDE"),
+		})
+		if err != nil {
+			log.Errorw("Failed to load secrets from Doppler", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "DOPPLER", Secrets: loaded})
+	}
+
+	if k8sSecrets := getListInput("KUBERNETES_SECRETS"); len(k8sSecrets) > 0 {
+		loaded, err := loadKubernetesSecrets(context.Background(), os.Getenv("INPUT_KUBERNETES_CONTEXT"), k8sSecrets)
+		if err != nil {
+			log.Errorw("Failed to load secrets from Kubernetes", err)
+			exit(1)
+		}
+		sources = append(sources, secretSource{Name: "KUBERNETES", Secrets: loaded})
+	}
+
+	secrets, secretOrigins, err := mergeSecretSources(sources, getListInput("SECRETS_PRECEDENCE"), os.Getenv("INPUT_DUPLICATE_SECRETS"))
+	if err != nil {
+		log.Errorw("Failed to merge secrets", err)
+		exit(1)
+	}
+
+	if environment := os.Getenv("INPUT_SECRETS_ENVIRONMENT"); environment != "" {
+		secrets = selectEnvironmentSecrets(secrets, environment, getListInput("SECRETS_ENVIRONMENTS"))
+	}
+
+	if opToken := os.Getenv("INPUT_OP_SERVICE_ACCOUNT_TOKEN"); opToken != "" {
+		if err := resolveOnePasswordReferences(context.Background(), opToken, secrets); err != nil {
+			log.Errorw("Failed to resolve 1Password secret references", err)
+			exit(1)
+		}
+	}
+
+	if getBoolInput("SECRET_TEMPLATES") {
+		if err := renderSecretTemplates(secrets, loadGitMetadata()); err != nil {
+			log.Errorw("Failed to render secret templates", err)
+			exit(1)
+		}
+	}
+
+	maskSecrets(secrets)
+	maskValue(lkApiSecret)
+	maskValue(getInputOrEnv("SLACK_TOKEN"))
+	for _, secret := range secrets {
+		logScrubber.add(string(secret.Value))
+	}
+	logScrubber.add(lkApiSecret)
+	logScrubber.add(os.Getenv("INPUT_GITHUB_TOKEN"))
+	logScrubber.add(getInputOrEnv("SLACK_TOKEN"))
+	logScrubber.add(getInputOrEnv("DISCORD_WEBHOOK_URL"))
+	logScrubber.add(getInputOrEnv("TEAMS_WEBHOOK_URL"))
+	logScrubber.add(getInputOrEnv("TELEGRAM_BOT_TOKEN"))
+	logScrubber.add(getInputOrEnv("GOOGLE_CHAT_WEBHOOK_URL"))
+	logScrubber.add(getInputOrEnv("WEBHOOK_URL"))
+	logScrubber.add(getInputOrEnv("WEBHOOK_SECRET"))
+	logScrubber.add(getInputOrEnv("PAGERDUTY_ROUTING_KEY"))
+	logScrubber.add(os.Getenv("INPUT_GITHUB_APP_PRIVATE_KEY"))
+
+	// the release version is not secret, it is passed to the agent so it can report it
+	if name := os.Getenv("INPUT_RELEASE_VERSION_SECRET"); name != "" {
+		if version := releaseVersion(); version != "" {
+			if slices.ContainsFunc(secrets, func(s *livekit.AgentSecret) bool { return s.Name == name }) {
+				log.Warnw("Secret already provided, not setting it to the release version", nil, "secret", name)
+			} else {
+				secrets = append(secrets, &livekit.AgentSecret{Name: name, Value: []byte(version)})
 			}
+		}
+	}
 
-			secretName := strings.TrimSpace(secretParts[0])
-			secretValue := strings.TrimSpace(secretParts[1])
-			log.Infow("Loading secret from SECRET_LIST", "secret", secretName)
-			secrets = append(secrets, &livekit.AgentSecret{
-				Name:  secretName,
-				Value: []byte(secretValue),
-			})
+	if errs := validateSecrets(secrets); len(errs) > 0 {
+		for _, err := range errs {
+			log.Errorw("Invalid secret", err)
+		}
+		exit(1)
+	}
+	for _, warning := range checkSecretSizes(secrets) {
+		log.Warnw("Secrets may be rejected by LiveKit Cloud", warning)
+	}
+
+	// fail before any API call when operations that send secrets are missing some
+	switch operation {
+	case "create", "deploy", "upsert", "preview", "update-secrets", "clone", "plan", "diff-secrets":
+		required := getListInput("REQUIRED_SECRETS")
+		if lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile); err == nil && exists && lkConfig.HasAgent() {
+			required = append(required, lkConfig.Agent.RequiredSecrets...)
+		}
+		if missing := missingSecrets(required, secrets); len(missing) > 0 {
+			log.Errorw("Required secrets are missing", nil, "secrets", missing)
+			exit(1)
 		}
 	}
+	endGroup()
 
 	client, err := cloudagents.New(
 		cloudagents.WithProject(lkUrl, lkApiKey, lkApiSecret),
@@ -142,8 +409,191 @@ func main() {
 	)
 	if err != nil {
 		log.Errorw("Failed to create agent client", err)
-		os.Exit(1)
+		exit(1)
+	}
+
+	if getBoolInput("DEPLOY_LOCK") {
+		switch operation {
+		case "create", "deploy", "upsert", "preview", "cleanup-preview", "rollback", "update-secrets", "scale", "regions", "update-metadata", "delete":
+			acquireDeployLock(operation, workingDir, timeoutDuration, intervalDuration)
+		}
+	}
+
+	if getBoolInput("IDEMPOTENT") {
+		if !getBoolInput("GITHUB_DEPLOYMENT") {
+			log.Errorw("IDEMPOTENT requires GITHUB_DEPLOYMENT, the deployments record which runs deployed", nil)
+			exit(1)
+		}
+		switch operation {
+		case "deploy", "upsert", "preview", "rollback":
+			if alreadyDeployed(operation, workingDir) {
+				writeAgentOutputs(client, workingDir)
+				exit(0)
+			}
+		}
+	}
+
+	if getBoolInput("SKIP_UNCHANGED") {
+		switch operation {
+		case "deploy", "upsert":
+			unchanged := sourceUnchanged(workingDir, os.Getenv("INPUT_BASE_SHA"))
+			if err := setOutput("skipped", strconv.FormatBool(unchanged)); err != nil {
+				log.Warnw("Failed to write output", err, "output", "skipped")
+			}
+			if unchanged {
+				writeAgentOutputs(client, workingDir)
+				exit(0)
+			}
+		}
+	}
+
+	// the hash of the archive about to be uploaded, recorded on the GitHub
+	// deployment so SKIP_SAME_SOURCE can compare with it
+	var uploadHash string
+	if getBoolInput("GITHUB_DEPLOYMENT") {
+		switch operation {
+		case "create", "deploy", "upsert", "preview", "clone":
+			uploadHash, err = sourceArchiveHash(newSourceFS(workingDir), sourceExcludes(workingDir))
+			if err != nil {
+				log.Errorw("Failed to hash agent source", err)
+				exit(1)
+			}
+		}
+	}
+
+	if getBoolInput("SKIP_SAME_SOURCE") {
+		if !getBoolInput("GITHUB_DEPLOYMENT") {
+			log.Errorw("SKIP_SAME_SOURCE requires GITHUB_DEPLOYMENT, the deployments record the hash of each upload", nil)
+			exit(1)
+		}
+		switch operation {
+		case "deploy", "upsert":
+			same := sourceMatchesLastDeploy(client, workingDir, uploadHash)
+			if err := setOutput("skipped", strconv.FormatBool(same)); err != nil {
+				log.Warnw("Failed to write output", err, "output", "skipped")
+			}
+			if same {
+				writeAgentOutputs(client, workingDir)
+				exit(0)
+			}
+		}
+	}
+
+	if getBoolInput("GITHUB_DEPLOYMENT") {
+		switch operation {
+		case "create", "deploy", "upsert", "preview", "rollback", "clone":
+			startDeployment(client, operation, workingDir, uploadHash)
+		}
+	}
+
+	if getBoolInput("COMMIT_STATUS") {
+		switch operation {
+		case "create", "deploy", "upsert", "preview", "rollback", "clone":
+			startCommitStatus(operation)
+		}
+	}
+
+	var checkRun *githubCheckRun
+	if getBoolInput("CHECK_RUN") {
+		switch operation {
+		case "create", "deploy", "upsert", "preview":
+			checkRun = startCheckRun()
+		}
+	}
+
+	var buildLogFile, manifestFile string
+	switch operation {
+	case "create", "deploy", "upsert", "preview":
+		buildLogFile = os.Getenv("INPUT_BUILD_LOG_FILE")
+		manifestFile = os.Getenv("INPUT_MANIFEST_FILE")
+	}
+
+	stepSummary := getBoolInput("STEP_SUMMARY")
+	if getInputOrEnv("PAGERDUTY_ROUTING_KEY") != "" {
+		switch operation {
+		case "status", "health":
+			pagerDutyAlerts = true
+		}
+		switch severity := strings.ToLower(os.Getenv("INPUT_PAGERDUTY_SEVERITY")); severity {
+		case "", "critical", "error", "warning", "info":
+		default:
+			log.Errorw("Invalid PAGERDUTY_SEVERITY, expected critical, error, warning or info", nil, "value", severity)
+			exit(1)
+		}
+	}
+
+	if alertStateFile = os.Getenv("INPUT_ALERT_STATE_FILE"); alertStateFile != "" {
+		switch operation {
+		case "status", "health":
+			alertRepeatAfter = getDurationInput("ALERT_REPEAT_AFTER", 0)
+			state, err := loadAlertState(alertStateFile)
+			if err != nil {
+				log.Errorw("Failed to load alert state", err, "path", alertStateFile)
+				exit(1)
+			}
+			alerts = state
+		}
+	}
+
+	// later jobs append their notifications to the thread of an earlier one
+	slackThreadTS = strings.TrimSpace(os.Getenv("INPUT_SLACK_THREAD_TS"))
+	var notifyEvents map[string]bool
+	switch operation {
+	case "create", "deploy", "upsert", "preview":
+		notifyEvents = deployNotifyEvents()
 	}
+	if notifyEvents[eventStarted] {
+		notifyStarted(operation, workingDir)
+	}
+	prComment := false
+	if getBoolInput("PR_COMMENT") {
+		switch operation {
+		case "create", "deploy", "upsert", "preview", "rollback", "clone", "status", "status-retry", "wait":
+			prComment = true
+		}
+	}
+	onExit(func(code int) {
+		report := newOperationReport(client, workingDir, operation, code, time.Since(startTime))
+		result.setReport(report)
+
+		// failures include the build log when the build was started, as an
+		// earlier failure would show the previous build's
+		notifyBuildLog := code != 0 && notifyEvents[eventFailed] && notifyLogLines > 0 && buildStarted()
+		var buildLog []string
+		if (checkRun != nil || buildLogFile != "" || notifyBuildLog) && report.AgentID != "" {
+			lines, err := fetchBuildLog(client, report.AgentID)
+			if err != nil {
+				log.Warnw("Failed to fetch build logs", err)
+			}
+			buildLog = lines
+		}
+
+		if code == 0 && notifyEvents[eventSucceeded] {
+			notify(successNotification(report))
+		} else if code != 0 && notifyEvents[eventFailed] {
+			var excerpt []string
+			if notifyBuildLog {
+				excerpt = buildLog
+			}
+			notify(failureNotification(report, excerpt))
+		}
+		if stepSummary {
+			writeOperationSummary(report)
+		}
+		if prComment {
+			postPullRequestComment(report, workingDir)
+		}
+		if manifestFile != "" {
+			writeManifest(manifestFile, report, workingDir, secrets, startTime)
+		}
+
+		if buildLogFile != "" {
+			writeBuildLog(buildLogFile, buildLog)
+		}
+		if checkRun != nil {
+			completeCheckRun(checkRun, report, buildLog)
+		}
+	})
 
 	// get the subdomain from the lkUrl
 	subdomain := strings.Split(lkUrl, ".")[0]
@@ -152,162 +602,776 @@ func main() {
 		log.Infow("No secrets loaded")
 	}
 
+	// /tmp/shared is not kept between jobs, so INCREMENTAL needs a state file
+	// the workflow caches
+	stateFile := os.Getenv("INPUT_SECRETS_STATE_FILE")
+	if stateFile == "" {
+		if operation == "update-secrets" && getBoolInput("INCREMENTAL") {
+			log.Errorw("INCREMENTAL requires SECRETS_STATE_FILE, cached between runs with actions/cache", nil)
+			exit(1)
+		}
+		stateFile = defaultSecretStateFile
+	}
+
+	if getBoolInput("SECRETS_REPORT") {
+		switch operation {
+		case "create", "deploy", "upsert", "update-secrets", "clone", "plan", "diff-secrets":
+			report, err := buildSecretReport(client, workingDir, secrets, secretOrigins, os.Getenv("INPUT_SECRETS_ENVIRONMENT"), stateFile)
+			if err != nil {
+				log.Errorw("Failed to build secrets report", err)
+				exit(1)
+			}
+			if err := writeSecretReport(report); err != nil {
+				log.Errorw("Failed to write secrets report", err)
+				exit(1)
+			}
+		}
+	}
+
 	switch operation {
 	case "create":
 		createAgent(client, subdomain, secrets, workingDir, region)
+		writeAgentOutputs(client, workingDir)
 	case "deploy":
-		deployAgent(client, secrets, workingDir)
+		deployAgent(client, secrets, workingDir, secretsMode)
+		writeAgentOutputs(client, workingDir)
+	case "preview":
+		deployPreviewAgent(client, subdomain, secrets, workingDir, region, os.Getenv("INPUT_AGENT_NAME"), secretsMode)
+		writeAgentOutputs(client, workingDir)
+	case "cleanup-preview":
+		cleanupPreviewAgent(client, workingDir, os.Getenv("INPUT_AGENT_NAME"), getBoolInput("DRY_RUN"))
+	case "upsert":
+		if _, err := os.Stat(filepath.Join(workingDir, LiveKitTOMLFile)); err == nil {
+			deployAgent(client, secrets, workingDir, secretsMode)
+		} else {
+			createAgent(client, subdomain, secrets, workingDir, region)
+		}
+		writeAgentOutputs(client, workingDir)
 	case "status":
 		err := agentStatus(client, workingDir)
+		writeAgentOutputs(client, workingDir)
 		if err != nil {
 			log.Errorw("Failed to get agent status", err)
-			os.Exit(1)
+			exit(1)
 		}
-	case "status-retry":
-		log.Debugw("Starting agent status retry", "timeout", timeoutDuration)
-		err := agentStatusRetry(client, workingDir, timeoutDuration)
+	case "status-retry", "wait":
+		log.Debugw("Starting agent status retry", "timeout", timeoutDuration, "interval", intervalDuration)
+		err := agentStatusRetry(client, workingDir, timeoutDuration, intervalDuration)
+		writeAgentOutputs(client, workingDir)
 		if err != nil {
 			log.Errorw("Failed to get agent status", err)
-			os.Exit(1)
+			exit(1)
 		}
 		log.Infow("Agent status check completed", "status", "running")
 	case "delete":
 		deleteAgent(client, workingDir)
 	case "delete-multi":
 		deleteAgentMulti(client, agentIds)
+	case "destroy-by-name":
+		deleteAgentsByName(client, os.Getenv("INPUT_AGENT_NAME"), getBoolInput("DRY_RUN"))
+	case "rollback":
+		rollbackAgent(client, workingDir, version)
+	case "logs":
+		agentLogs(client, workingDir, logType, region, logTail, timeoutDuration)
+	case "update-secrets":
+		updateAgentSecrets(client, secrets, workingDir, getBoolInput("RESTART"), getBoolInput("INCREMENTAL"), stateFile)
+	case "metrics":
+		agentMetrics(client, workingDir, os.Getenv("INPUT_METRICS_FILE"))
+	case "health":
+		agentHealth(client, workingDir, getIntInput("HEALTH_MIN_REPLICAS"), maxDeployAge)
+	case "adopt":
+		adoptAgent(client, subdomain, workingDir, os.Getenv("INPUT_AGENT_ID"), os.Getenv("INPUT_AGENT_NAME"))
+	case "export":
+		exportAgent(client, workingDir, os.Getenv("INPUT_EXPORT_FILE"))
+	case "diff-secrets":
+		diffAgentSecrets(client, secrets, workingDir)
+	case "secrets-list":
+		listAgentSecrets(client, workingDir)
+	case "regions":
+		reconcileRegions(client, workingDir, getBoolInput("DRY_RUN"))
+	case "update-metadata":
+		updateAgentMetadata(client, workingDir, os.Getenv("INPUT_AGENT_NAME"))
+	case "clone":
+		cloneAgent(client, subdomain, secrets, workingDir, os.Getenv("INPUT_SOURCE_AGENT_ID"))
+	case "versions":
+		listAgentVersions(client, workingDir)
+	case "plan":
+		planDeploy(client, secrets, workingDir, secretsMode)
+	case "scale":
+		scaleAgent(client, workingDir, getIntInput("REPLICAS"), getIntInput("MAX_REPLICAS"))
 	default:
 		log.Errorw("Invalid operation", nil, "operation", operation)
-		os.Exit(1)
+		exit(1)
 	}
+
+	exit(0)
 }
 
-func sendSlackNotification(message string) {
-	slackToken := os.Getenv("SLACK_TOKEN")
-	slackChannel := os.Getenv("SLACK_CHANNEL")
+// handleCancellation exits with a failure when the workflow run is cancelled,
+// so the exit hooks settle pending commit statuses, deployments and check runs.
+// A build that was already started keeps running on LiveKit Cloud, the API has
+// no way to cancel it.
+func handleCancellation() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Warnw("Operation cancelled", nil, "signal", sig.String())
+		exit(1)
+	}()
+}
 
-	if slackToken == "" || slackChannel == "" {
-		log.Infow("Slack notification skipped - token or channel not configured")
-		return
+// getInputOrEnv reads an input, falling back to the env var of the same name
+// for workflows that set it with env: rather than with:.
+func getInputOrEnv(name string) string {
+	if value := strings.TrimSpace(os.Getenv("INPUT_" + name)); value != "" {
+		return value
 	}
+	return strings.TrimSpace(os.Getenv(name))
+}
 
-	api := slack.New(slackToken)
-	_, _, err := api.PostMessage(
-		slackChannel,
-		slack.MsgOptionText(message, false),
-	)
+// startDeployment creates a GitHub Deployment for the operation and settles
+// its status when the action exits. A successful status records the agent
+// version live after the operation.
+func startDeployment(client *cloudagents.Client, operation string, workingDir string, sourceHash string) {
+	gh, err := newGitHubClient()
+	if err != nil {
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
+	}
 
+	description := fmt.Sprintf("LiveKit Cloud agent %s", operation)
+	if version := releaseVersion(); version != "" {
+		description += " of " + version
+	}
+	payload := newDeploymentPayload(operation, workingDir)
+	payload.SourceHash = sourceHash
+	deployment, err := startGitHubDeployment(context.Background(), gh, deploymentEnvironment(), description, payload)
 	if err != nil {
-		log.Errorw("Failed to send Slack notification", err)
-	} else {
-		log.Infow("Slack notification sent", "channel", slackChannel)
+		log.Errorw("Failed to start GitHub deployment", err)
+		exit(1)
 	}
+	onExit(func(code int) {
+		state, description := "success", "Deployed to LiveKit Cloud"
+		if code != 0 {
+			state, description = "failure", "LiveKit Cloud deploy failed"
+		} else if version := currentAgentVersion(client, workingDir); version != "" {
+			description = fmt.Sprintf(deployedVersionDescription, version)
+		}
+		if err := deployment.setStatus(context.Background(), state, description); err != nil {
+			log.Warnw("Failed to complete GitHub deployment", err)
+		}
+	})
 }
 
-func agentStatusRetry(client *cloudagents.Client, workingDir string, timeoutDuration time.Duration) error {
-	startTime := time.Now()
-	for {
-		err := agentStatus(client, workingDir)
-		if err == nil {
-			return nil
-		}
+// acquireDeployLock takes the deploy lock for the agent in workingDir, and
+// releases it when the action exits.
+func acquireDeployLock(operation string, workingDir string, timeout time.Duration, interval time.Duration) {
+	gh, err := newGitHubClient()
+	if err != nil {
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
+	}
 
-		if time.Since(startTime) >= timeoutDuration {
-			return fmt.Errorf("timeout reached after %v", timeoutDuration)
+	policy := os.Getenv("INPUT_DEPLOY_LOCK_POLICY")
+	switch policy {
+	case "":
+		policy = "wait"
+	case "wait", "fail":
+	default:
+		log.Errorw("Invalid DEPLOY_LOCK_POLICY, expected wait or fail", nil, "value", policy)
+		exit(1)
+	}
+	staleAfter := getDurationInput("DEPLOY_LOCK_STALE_AFTER", time.Hour)
+
+	// preview agents are locked by name, so previews of different pull
+	// requests don't wait for each other or for the base agent
+	lkConfig, exists, _ := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	key := "path-" + filepath.ToSlash(filepath.Clean(workingDir))
+	switch {
+	case operation == "preview" || operation == "cleanup-preview":
+		name, err := previewAgentName(previewAgentBase(lkConfig, os.Getenv("INPUT_AGENT_NAME")))
+		if err != nil {
+			log.Errorw("Failed to derive preview agent name", err)
+			exit(1)
 		}
+		key = "preview-" + name
+	case exists && lkConfig.HasAgent() && lkConfig.Agent.ID != "":
+		key = lkConfig.Agent.ID
+	}
 
-		log.Infow("Failed to get running agent", "error", err)
-		time.Sleep(5 * time.Second)
+	lock := newDeployLock(gh, key)
+	if err := lock.acquire(context.Background(), policy == "wait", timeout, interval, staleAfter); err != nil {
+		log.Errorw("Failed to acquire deploy lock", err, "lock", lock.ref)
+		exit(1)
 	}
+	log.Infow("Deploy lock acquired", "lock", lock.ref)
+	onExit(func(code int) {
+		if err := lock.release(context.Background()); err != nil {
+			log.Warnw("Failed to release deploy lock", err, "lock", lock.ref)
+		}
+	})
 }
 
-func agentStatus(client *cloudagents.Client, workingDir string) error {
-	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+// alreadyDeployed reports whether an earlier attempt of this workflow run
+// already completed the operation successfully, so a re-run does not push an
+// identical build again.
+func alreadyDeployed(operation string, workingDir string) bool {
+	gh, err := newGitHubClient()
 	if err != nil {
-		return err
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
 	}
 
-	if !exists {
-		return fmt.Errorf("livekit.toml not found")
+	id, err := findSuccessfulDeployment(context.Background(), gh, deploymentEnvironment(), newDeploymentPayload(operation, workingDir))
+	if err != nil {
+		log.Errorw("Failed to check for an earlier deployment", err)
+		exit(1)
 	}
+	if id == 0 {
+		return false
+	}
+	log.Infow("Already deployed by an earlier attempt of this run, skipping", "deployment", id, "run", os.Getenv("GITHUB_RUN_ID"))
+	return true
+}
 
-	log.Infow("Getting agent status", "agent", lkConfig.Agent.ID)
-
-	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
-		AgentId: lkConfig.Agent.ID,
-	})
+// sourceUnchanged reports whether no file under workingDir changed since base,
+// or since the commit of the last successful GitHub deployment of workingDir
+// when base is not set. Whenever that cannot be determined, the source is
+// considered changed.
+func sourceUnchanged(workingDir string, base string) bool {
+	gh, err := newGitHubClient()
 	if err != nil {
-		return fmt.Errorf("failed to get agent")
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
 	}
+	dir := filepath.ToSlash(filepath.Clean(workingDir))
 
-	if len(res.Agents) == 0 {
-		return fmt.Errorf("agent not found")
+	// github.event.before is all zeros for the first push of a branch
+	if base != "" && strings.Trim(base, "0") == "" {
+		log.Infow("No base commit to compare with, deploying", "base", base)
+		return false
+	}
+	if base == "" {
+		base, err = lastDeployedCommit(context.Background(), gh, deploymentEnvironment(), dir)
+		if err != nil {
+			log.Errorw("Failed to find the last deployed commit", err)
+			exit(1)
+		}
+		if base == "" {
+			log.Infow("No previous deployment of the working directory, deploying", "path", dir)
+			return false
+		}
 	}
 
-	for _, agent := range res.Agents {
-		for _, regionalAgent := range agent.AgentDeployments {
-			if regionalAgent.Status != "Running" {
-				sendSlackNotification(fmt.Sprintf("Agent %s is not running", lkConfig.Agent.ID))
-				return fmt.Errorf("agent id %s is not running %s", lkConfig.Agent.ID, regionalAgent.Status)
-			}
+	files, complete, err := changedFiles(context.Background(), gh, base, commitSHA())
+	if err != nil {
+		log.Errorw("Failed to list changed files", err)
+		exit(1)
+	}
+	if !complete {
+		log.Infow("Cannot tell which files changed since the base commit, deploying", "base", base)
+		return false
+	}
+	for _, file := range files {
+		if dir == "." || file == dir || strings.HasPrefix(file, dir+"/") {
+			log.Infow("Working directory changed, deploying", "base", base, "file", file)
+			return false
 		}
 	}
 
-	log.Infow("Agent status", "agent", lkConfig.Agent.ID, "status", res.Agents[0].AgentDeployments[0].Status)
-	return nil
+	log.Infow("Working directory unchanged since the base commit, skipping deploy", "base", base, "path", dir)
+	return true
 }
 
-func deployAgent(client *cloudagents.Client, secrets []*livekit.AgentSecret, workingDir string) {
+// currentAgentVersion returns the version of the agent in workingDir, or ""
+// when it cannot be fetched.
+func currentAgentVersion(client *cloudagents.Client, workingDir string) string {
 	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil || !exists || !lkConfig.HasAgent() || lkConfig.Agent.ID == "" {
+		return ""
+	}
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil || len(res.Agents) == 0 {
+		return ""
+	}
+	return res.Agents[0].Version
+}
+
+// sourceMatchesLastDeploy reports whether hash, the hash of the archive about
+// to be uploaded from workingDir, is the one recorded on the last successful
+// GitHub deployment of workingDir, and the agent still runs the version that
+// deployment left live. Whenever that cannot be determined, the source is
+// considered changed.
+func sourceMatchesLastDeploy(client *cloudagents.Client, workingDir string, hash string) bool {
+	gh, err := newGitHubClient()
 	if err != nil {
-		log.Errorw("Failed to load livekit.toml", err)
-		os.Exit(1)
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
 	}
+	dir := filepath.ToSlash(filepath.Clean(workingDir))
 
-	if !exists {
-		log.Errorw("livekit.toml not found", nil)
-		os.Exit(1)
+	last, err := lastSuccessfulDeployment(context.Background(), gh, deploymentEnvironment(), dir)
+	if err != nil {
+		log.Errorw("Failed to find the last deployment", err)
+		exit(1)
+	}
+	switch {
+	case last == nil:
+		log.Infow("No previous deployment of the working directory, deploying", "path", dir)
+		return false
+	case last.Payload.SourceHash == "":
+		// rollbacks and deployments made before the hash was recorded
+		log.Infow("Last deployment has no source hash, deploying", "deployment", last.ID, "operation", last.Payload.Operation)
+		return false
+	case last.Payload.SourceHash != hash:
+		log.Infow("Source differs from the last deployment, deploying", "hash", hash, "deployed", last.Payload.SourceHash)
+		return false
+	case last.Version == "":
+		// deployments made before the version was recorded
+		log.Infow("Last deployment has no agent version, deploying", "deployment", last.ID)
+		return false
 	}
 
-	if err := client.DeployAgent(
-		context.Background(),
-		lkConfig.Agent.ID,
-		os.DirFS(workingDir),
-		secrets,
-		[]string{LiveKitTOMLFile},
-	); err != nil {
-		log.Errorw("Failed to deploy agent", err)
-		os.Exit(1)
+	// a deploy from elsewhere or a rollback on the dashboard changes the
+	// running code without a GitHub deployment
+	if version := currentAgentVersion(client, workingDir); version != last.Version {
+		log.Infow("Agent was changed since the last deployment, deploying", "version", version, "deployed", last.Version)
+		return false
 	}
 
-	log.Infow("Agent deployed", "agent", lkConfig.Agent.ID)
+	log.Infow("Source identical to the last deployment, skipping deploy", "hash", hash, "deployment", last.ID, "commit", last.SHA)
+	return true
 }
 
-func createAgent(client *cloudagents.Client, subdomain string, secrets []*livekit.AgentSecret, workingDir string, region string) {
-	if _, err := os.Stat(fmt.Sprintf("%s/%s", workingDir, LiveKitTOMLFile)); err == nil {
-		log.Infow("livekit.toml already exists", "path", fmt.Sprintf("%s/%s", workingDir, LiveKitTOMLFile))
-		os.Exit(0)
+// environmentName is the environment being deployed to, DEPLOYMENT_ENVIRONMENT
+// or ENVIRONMENT, for labelling deploys and notifications. GitHub does not
+// expose the name of the job's environment to its steps, so it must be passed.
+func environmentName() string {
+	if environment := os.Getenv("INPUT_DEPLOYMENT_ENVIRONMENT"); environment != "" {
+		return environment
 	}
-	lkConfig := NewLiveKitTOML(subdomain).WithDefaultAgent()
-	var regions []string
-	if region != "" {
-		regions = []string{region}
+	return os.Getenv("INPUT_ENVIRONMENT")
+}
+
+func deploymentEnvironment() string {
+	if environment := environmentName(); environment != "" {
+		return environment
 	}
-	resp, err := client.CreateAgent(
-		context.Background(),
-		os.DirFS(workingDir),
-		secrets,
-		regions,
-		[]string{LiveKitTOMLFile},
-	)
+	return "production"
+}
+
+func newDeploymentPayload(operation string, workingDir string) deploymentPayload {
+	return deploymentPayload{
+		RunID:            os.Getenv("GITHUB_RUN_ID"),
+		RunAttempt:       os.Getenv("GITHUB_RUN_ATTEMPT"),
+		Operation:        operation,
+		WorkingDirectory: filepath.ToSlash(filepath.Clean(workingDir)),
+		Release:          releaseVersion(),
+	}
+}
+
+// startCommitStatus marks the commit status pending and settles it when the
+// action exits.
+func startCommitStatus(operation string) {
+	gh, err := newGitHubClient()
+	if err != nil {
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
+	}
+	statusContext := os.Getenv("INPUT_COMMIT_STATUS_CONTEXT")
+	if statusContext == "" {
+		statusContext = defaultCommitStatusContext
+	}
+
+	// e.g. "deploy to staging", so the status says which environment it is for
+	target := operation
+	if environment := environmentName(); environment != "" {
+		target += " to " + environment
+	}
+
+	if err := setCommitStatus(context.Background(), gh, statusContext, "pending", fmt.Sprintf("LiveKit Cloud agent %s in progress", target)); err != nil {
+		log.Errorw("Failed to set commit status", err)
+		exit(1)
+	}
+	onExit(func(code int) {
+		state, description := "success", fmt.Sprintf("LiveKit Cloud agent %s succeeded", target)
+		if code != 0 {
+			state, description = "failure", fmt.Sprintf("LiveKit Cloud agent %s failed", target)
+		}
+		if err := setCommitStatus(context.Background(), gh, statusContext, state, description); err != nil {
+			log.Warnw("Failed to set commit status", err)
+		}
+	})
+}
+
+// startCheckRun creates an in progress check run for the operation.
+func startCheckRun() *githubCheckRun {
+	gh, err := newGitHubClient()
+	if err != nil {
+		log.Errorw("Failed to create GitHub client", err)
+		exit(1)
+	}
+	name := os.Getenv("INPUT_CHECK_RUN_NAME")
+	if name == "" {
+		name = defaultCheckRunName
+	}
+
+	checkRun, err := startGitHubCheckRun(context.Background(), gh, name)
+	if err != nil {
+		log.Errorw("Failed to create check run", err)
+		exit(1)
+	}
+	log.Infow("Check run created", "name", name)
+	return checkRun
+}
+
+// completeCheckRun attaches the operation's report and the tail of the
+// agent's build log to the check run and marks it completed.
+func completeCheckRun(checkRun *githubCheckRun, report *operationReport, buildLog []string) {
+	if len(buildLog) > checkRunLogLines {
+		buildLog = buildLog[len(buildLog)-checkRunLogLines:]
+	}
+
+	summary := report.markdown(fmt.Sprintf("### LiveKit Cloud Agent `%s`", report.Operation))
+	if err := checkRun.complete(context.Background(), report.Code == 0, summary, buildLog); err != nil {
+		log.Warnw("Failed to complete check run", err)
+	}
+}
+
+// writeManifest writes the deployment manifest to path and sets the manifest
+// output.
+func writeManifest(path string, report *operationReport, workingDir string, secrets []*livekit.AgentSecret, startTime time.Time) {
+	manifest, err := newDeploymentManifest(report, workingDir, secrets, startTime)
+	if err != nil {
+		log.Warnw("Failed to build deployment manifest", err)
+		return
+	}
+	if err := manifest.write(path); err != nil {
+		log.Warnw("Failed to write deployment manifest", err, "path", path)
+		return
+	}
+	if err := setOutput("manifest", path); err != nil {
+		log.Warnw("Failed to write output", err, "output", "manifest")
+	}
+	log.Infow("Deployment manifest written", "path", path)
+}
+
+// writeBuildLog writes the full build log to path and sets the build_log
+// output, so it can be uploaded as a workflow artifact.
+func writeBuildLog(path string, buildLog []string) {
+	if len(buildLog) == 0 {
+		log.Warnw("No build log to write", nil, "path", path)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Warnw("Failed to write build log", err, "path", path)
+		return
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(buildLog, "\n")+"\n"), 0644); err != nil {
+		log.Warnw("Failed to write build log", err, "path", path)
+		return
+	}
+	if err := setOutput("build_log", path); err != nil {
+		log.Warnw("Failed to write output", err, "output", "build_log")
+	}
+	log.Infow("Build log written", "path", path, "lines", len(buildLog))
+}
+
+// postPullRequestComment creates or updates the sticky comment for the agent
+// in working directory on the pull request that triggered the workflow.
+func postPullRequestComment(report *operationReport, workingDir string) {
+	number := pullRequestNumber()
+	if number == 0 {
+		log.Debugw("Not a pull request event, skipping pull request comment")
+		return
+	}
+	gh, err := newGitHubClient()
+	if err != nil {
+		log.Warnw("Failed to create GitHub client", err)
+		return
+	}
+
+	heading := fmt.Sprintf("### LiveKit Cloud Agent `%s`", report.Operation)
+	if err := upsertPullRequestComment(context.Background(), gh, number, workingDir, report.markdown(heading)); err != nil {
+		log.Warnw("Failed to post pull request comment", err)
+		return
+	}
+	log.Infow("Pull request comment posted", "pull_request", number)
+}
+
+var (
+	exitHooks []func(code int)
+	exitMu    sync.Mutex
+	// outputPrefix namespaces the outputs written on exit, see OUTPUT_PREFIX
+	outputPrefix string
+	// exiting is set while the exit hooks run
+	exiting bool
+)
+
+// onExit registers a hook to run before the action exits, whether the
+// operation succeeded or not.
+func onExit(hook func(code int)) {
+	exitHooks = append(exitHooks, hook)
+}
+
+func exit(code int) {
+	// a cancellation may exit while the operation is exiting, only one runs the hooks
+	exitMu.Lock()
+	// a cancelled build still has stderr redirected
+	endBuildOutput()
+	// errors that end the operation stay visible outside of collapsed groups
+	endGroup()
+	exiting = true
+	hooks := exitHooks
+	exitHooks = nil
+	if len(hooks) > 0 {
+		startGroup("Report results")
+	}
+	for _, hook := range hooks {
+		hook(code)
+	}
+	endGroup()
+	if code == 0 && notificationFailed {
+		log.Errorw("Failed to send notifications, failing as NOTIFY_FAILURE_POLICY is fail", nil)
+		code = 1
+	}
+	// written after the hooks, so they see everything the hooks set
+	writeResultOutput(code)
+	if outputPrefix != "" {
+		writeNamespacedOutputs(outputPrefix, os.Getenv("INPUT_OUTPUTS_DIR"))
+	}
+	os.Exit(code)
+}
+
+// agentStatusRetry polls the agent until it runs in every region. Rollouts
+// pass through other statuses, so polls don't alert, only the timeout does.
+func agentStatusRetry(client *cloudagents.Client, workingDir string, timeoutDuration time.Duration, intervalDuration time.Duration) error {
+	startTime := time.Now()
+	for {
+		agent, agentID, err := checkAgentRunning(client, workingDir)
+		if err == nil {
+			agentRecovered(agent, agentID)
+			return nil
+		}
+
+		if time.Since(startTime) >= timeoutDuration {
+			if agent != nil {
+				alertAgent(client, agent, agentID, fmt.Sprintf("Agent %s is not running after %v", agentID, timeoutDuration))
+			}
+			return fmt.Errorf("timeout reached after %v", timeoutDuration)
+		}
+
+		log.Infow("Failed to get running agent", "error", err)
+		time.Sleep(intervalDuration)
+	}
+}
+
+// agentStatus checks that the agent runs in every region, alerting when it
+// doesn't.
+func agentStatus(client *cloudagents.Client, workingDir string) error {
+	agent, agentID, err := checkAgentRunning(client, workingDir)
+	if err != nil {
+		if agent != nil {
+			alertAgent(client, agent, agentID, fmt.Sprintf("Agent %s is not running", agentID))
+		}
+		return err
+	}
+	agentRecovered(agent, agentID)
+	return nil
+}
+
+// checkAgentRunning returns the agent and an error when it does not run in
+// every region. The agent is nil when it could not be fetched.
+func checkAgentRunning(client *cloudagents.Client, workingDir string) (*livekit.AgentInfo, string, error) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !exists {
+		return nil, "", fmt.Errorf("livekit.toml not found")
+	}
+
+	log.Infow("Getting agent status", "agent", lkConfig.Agent.ID)
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		return nil, lkConfig.Agent.ID, fmt.Errorf("failed to get agent")
+	}
+
+	if len(res.Agents) == 0 {
+		return nil, lkConfig.Agent.ID, fmt.Errorf("agent not found")
+	}
+
+	for _, agent := range res.Agents {
+		for _, regionalAgent := range agent.AgentDeployments {
+			if regionalAgent.Status != "Running" {
+				return agent, lkConfig.Agent.ID, fmt.Errorf("agent id %s is not running %s", lkConfig.Agent.ID, regionalAgent.Status)
+			}
+		}
+	}
+
+	log.Infow("Agent status", "agent", lkConfig.Agent.ID, "status", res.Agents[0].AgentDeployments[0].Status)
+	return res.Agents[0], lkConfig.Agent.ID, nil
+}
+
+func deployAgent(client *cloudagents.Client, secrets []*livekit.AgentSecret, workingDir string, secretsMode string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	if notificationsConfigured() {
+		recordDeployChanges(client, lkConfig.Agent.ID, secrets, secretsMode, workingDir)
+	}
+
+	// in sync mode the provided secrets become the agent's full secret set and
+	// every other secret is deleted before the deploy
+	if secretsMode == "sync" {
+		startGroup("Sync agent secrets")
+		syncAgentSecrets(client, lkConfig.Agent.ID, secrets)
+		secrets = nil
+	}
+
+	// in merge mode the secrets are written ahead of the deploy, which then
+	// carries none, so cloud-side secrets that were not provided are kept
+	if secretsMode == "merge" {
+		if len(secrets) > 0 {
+			startGroup("Merge agent secrets")
+			resp, err := client.UpdateAgentSecrets(context.Background(), &livekit.UpdateAgentSecretsRequest{
+				AgentId: lkConfig.Agent.ID,
+				Secrets: secrets,
+			})
+			if err != nil {
+				log.Errorw("Failed to update agent secrets", err)
+				exit(1)
+			}
+			if !resp.Success {
+				log.Errorw("Failed to update agent secrets", errors.New(resp.Message))
+				exit(1)
+			}
+			log.Infow("Agent secrets merged", "agent", lkConfig.Agent.ID, "count", len(secrets))
+		}
+		secrets = nil
+	}
+
+	startGroup(buildGroup)
+	excludes := sourceExcludes(workingDir)
+	checkUploadSize(workingDir, excludes)
+	streamBuildOutput()
+	err = client.DeployAgent(
+		context.Background(),
+		lkConfig.Agent.ID,
+		newSourceFS(workingDir),
+		secrets,
+		excludes,
+	)
+	endBuildOutput()
+	if err != nil {
+		log.Errorw("Failed to deploy agent", err)
+		exit(1)
+	}
+	endGroup()
+	notify(notification{Event: eventProgress, Message: fmt.Sprintf("Agent %s uploaded and built", lkConfig.Agent.ID), AgentID: lkConfig.Agent.ID})
+
+	log.Infow("Agent deployed", "agent", lkConfig.Agent.ID)
+}
+
+func syncAgentSecrets(client *cloudagents.Client, agentId string, secrets []*livekit.AgentSecret) {
+	if len(secrets) == 0 {
+		log.Errorw("Refusing to sync no secrets, which would delete every agent secret", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: agentId,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent secrets", err)
+		exit(1)
+	}
+	_, removed, _ := diffSecretNames(secrets, res.Secrets)
+
+	resp, err := client.UpdateAgentSecrets(context.Background(), &livekit.UpdateAgentSecretsRequest{
+		AgentId:   agentId,
+		Overwrite: true,
+		Secrets:   secrets,
+	})
+	if err != nil {
+		log.Errorw("Failed to sync agent secrets", err)
+		exit(1)
+	}
+	if !resp.Success {
+		log.Errorw("Failed to sync agent secrets", errors.New(resp.Message))
+		exit(1)
+	}
+
+	if len(removed) > 0 {
+		// the overwrite must have dropped every secret that was not provided
+		res, err = client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+			AgentId: agentId,
+		})
+		if err != nil {
+			log.Errorw("Failed to list agent secrets", err)
+			exit(1)
+		}
+		_, remaining, _ := diffSecretNames(secrets, res.Secrets)
+		if len(remaining) > 0 {
+			log.Errorw("Failed to delete agent secrets", nil, "secrets", remaining)
+			exit(1)
+		}
+	}
+	for _, name := range removed {
+		log.Infow("Secret deleted", "secret", name)
+	}
+
+	log.Infow("Agent secrets synced", "agent", agentId, "count", len(secrets), "deleted", len(removed))
+}
+
+func createAgent(client *cloudagents.Client, subdomain string, secrets []*livekit.AgentSecret, workingDir string, region string) {
+	if _, err := os.Stat(fmt.Sprintf("%s/%s", workingDir, LiveKitTOMLFile)); err == nil {
+		log.Infow("livekit.toml already exists", "path", fmt.Sprintf("%s/%s", workingDir, LiveKitTOMLFile))
+		exit(0)
+	}
+	lkConfig := NewLiveKitTOML(subdomain).WithDefaultAgent()
+	var regions []string
+	if region != "" {
+		regions = []string{region}
+	}
+	startGroup(buildGroup)
+	excludes := sourceExcludes(workingDir)
+	checkUploadSize(workingDir, excludes)
+	streamBuildOutput()
+	resp, err := client.CreateAgent(
+		context.Background(),
+		newSourceFS(workingDir),
+		secrets,
+		regions,
+		excludes,
+	)
+	endBuildOutput()
 	if err != nil {
 		log.Errorw("Failed to create agent", err)
-		os.Exit(1)
+		exit(1)
 	}
+	endGroup()
+	notify(notification{Event: eventProgress, Message: fmt.Sprintf("Agent %s uploaded and built", resp.AgentId), AgentID: resp.AgentId})
 
 	lkConfig.Agent.ID = resp.AgentId
 	if err := lkConfig.SaveTOMLFile(workingDir, LiveKitTOMLFile); err != nil {
 		log.Errorw("Failed to save livekit.toml", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	log.Infow("Agent created", "agent", resp.AgentId)
@@ -317,12 +1381,12 @@ func deleteAgent(client *cloudagents.Client, workingDir string) {
 	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
 	if err != nil {
 		log.Errorw("Failed to load livekit.toml", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if !exists {
 		log.Errorw("livekit.toml not found", nil)
-		os.Exit(1)
+		exit(1)
 	}
 
 	req := &livekit.DeleteAgentRequest{
@@ -332,7 +1396,7 @@ func deleteAgent(client *cloudagents.Client, workingDir string) {
 	_, err = client.DeleteAgent(context.Background(), req)
 	if err != nil {
 		log.Errorw("Failed to delete agent", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	log.Infow("Agent deleted", "agent", lkConfig.Agent.ID)
@@ -347,9 +1411,1118 @@ func deleteAgentMulti(client *cloudagents.Client, agentIds []string) {
 		_, err := client.DeleteAgent(context.Background(), req)
 		if err != nil {
 			log.Errorw("Failed to delete agent", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		log.Infow("Agent deleted", "agent", agentId)
 	}
 }
+
+// deleteAgentsByName deletes every agent whose name matches the pattern, which
+// may contain shell-style wildcards (e.g. my-agent-pr-*).
+func deleteAgentsByName(client *cloudagents.Client, pattern string, dryRun bool) {
+	if pattern == "" || strings.Trim(pattern, "*") == "" {
+		log.Errorw("AGENT_NAME must be set to a name or a pattern narrower than *", nil, "pattern", pattern)
+		exit(1)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		log.Errorw("Invalid agent name pattern", err, "pattern", pattern)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{})
+	if err != nil {
+		log.Errorw("Failed to list agents", err)
+		exit(1)
+	}
+
+	var agentIds []string
+	for _, agent := range res.Agents {
+		if ok, _ := path.Match(pattern, agent.AgentName); ok {
+			log.Infow("Agent matches name pattern", "agent", agent.AgentId, "name", agent.AgentName)
+			agentIds = append(agentIds, agent.AgentId)
+		}
+	}
+
+	if len(agentIds) == 0 {
+		log.Infow("No agents match name pattern", "pattern", pattern)
+		return
+	}
+	if dryRun {
+		log.Infow("Dry run, agents not deleted", "count", len(agentIds))
+		return
+	}
+
+	deleteAgentMulti(client, agentIds)
+}
+
+func rollbackAgent(client *cloudagents.Client, workingDir string, version string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	if version == "" {
+		version, err = previousAgentVersion(client, lkConfig.Agent.ID)
+		if err != nil {
+			log.Errorw("Failed to find previous agent version", err)
+			exit(1)
+		}
+	}
+
+	log.Infow("Rolling back agent", "agent", lkConfig.Agent.ID, "version", version)
+
+	resp, err := client.RollbackAgent(context.Background(), &livekit.RollbackAgentRequest{
+		AgentId: lkConfig.Agent.ID,
+		Version: version,
+	})
+	if err != nil {
+		log.Errorw("Failed to rollback agent", err)
+		exit(1)
+	}
+	if !resp.Success {
+		log.Errorw("Failed to rollback agent", errors.New(resp.Message))
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil || len(res.Agents) == 0 {
+		log.Infow("Agent rolled back", "agent", lkConfig.Agent.ID, "version", version)
+		return
+	}
+
+	log.Infow("Agent rolled back", "agent", lkConfig.Agent.ID, "version", res.Agents[0].Version)
+}
+
+// previousAgentVersion returns the most recent version created before the
+// currently deployed one.
+func previousAgentVersion(client *cloudagents.Client, agentId string) (string, error) {
+	res, err := client.ListAgentVersions(context.Background(), &livekit.ListAgentVersionsRequest{
+		AgentId: agentId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var current *livekit.AgentVersion
+	for _, v := range res.Versions {
+		if v.Current {
+			current = v
+			break
+		}
+	}
+	if current == nil {
+		return "", fmt.Errorf("no current version found for agent %s", agentId)
+	}
+
+	var previous *livekit.AgentVersion
+	for _, v := range res.Versions {
+		if v.Current || !v.CreatedAt.AsTime().Before(current.CreatedAt.AsTime()) {
+			continue
+		}
+		if previous == nil || v.CreatedAt.AsTime().After(previous.CreatedAt.AsTime()) {
+			previous = v
+		}
+	}
+	if previous == nil {
+		return "", fmt.Errorf("no previous version found for agent %s", agentId)
+	}
+
+	return previous.Version, nil
+}
+
+func agentLogs(client *cloudagents.Client, workingDir string, logType string, region string, tail int, timeoutDuration time.Duration) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	log.Infow("Fetching agent logs", "agent", lkConfig.Agent.ID, "type", logType, "region", region, "tail", tail)
+
+	// runtime logs are streamed until the connection is closed, so bound the
+	// request by the timeout and print whatever was collected
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
+	defer cancel()
+
+	w := newTailWriter(tail)
+	err = streamLogs(ctx, client, logType, lkConfig.Agent.ID, region, w)
+	if _, flushErr := w.WriteTo(os.Stdout); flushErr != nil {
+		log.Errorw("Failed to write agent logs", flushErr)
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		log.Errorw("Failed to fetch agent logs", err)
+		exit(1)
+	}
+}
+
+func updateAgentSecrets(client *cloudagents.Client, secrets []*livekit.AgentSecret, workingDir string, restart bool, incremental bool, stateFile string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	if len(secrets) == 0 {
+		log.Errorw("No secrets to update", nil)
+		exit(1)
+	}
+
+	var state *secretState
+	if incremental {
+		state, err = loadSecretState(stateFile, lkConfig.Agent.ID)
+		if err != nil {
+			log.Errorw("Failed to load secrets state file", err, "path", stateFile)
+			exit(1)
+		}
+		if len(state.Secrets) == 0 {
+			log.Warnw("No secrets state recorded for the agent, sending every secret; check that SECRETS_STATE_FILE is cached between runs", nil, "path", stateFile)
+		}
+		secrets = changedSecrets(client, lkConfig.Agent.ID, secrets, state)
+		if len(secrets) == 0 {
+			log.Infow("Agent secrets unchanged, nothing to update", "agent", lkConfig.Agent.ID)
+			return
+		}
+	}
+
+	resp, err := client.UpdateAgentSecrets(context.Background(), &livekit.UpdateAgentSecretsRequest{
+		AgentId: lkConfig.Agent.ID,
+		Secrets: secrets,
+	})
+	if err != nil {
+		log.Errorw("Failed to update agent secrets", err)
+		exit(1)
+	}
+	if !resp.Success {
+		log.Errorw("Failed to update agent secrets", errors.New(resp.Message))
+		exit(1)
+	}
+
+	log.Infow("Agent secrets updated", "agent", lkConfig.Agent.ID, "count", len(secrets))
+
+	if incremental {
+		recordSecretState(client, lkConfig.Agent.ID, secrets, state, stateFile)
+	}
+
+	if !restart {
+		return
+	}
+
+	restartResp, err := client.RestartAgent(context.Background(), &livekit.RestartAgentRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to restart agent", err)
+		exit(1)
+	}
+	if !restartResp.Success {
+		log.Errorw("Failed to restart agent", errors.New(restartResp.Message))
+		exit(1)
+	}
+
+	log.Infow("Agent restarted", "agent", lkConfig.Agent.ID)
+}
+
+// changedSecrets returns the secrets that were added or changed since they
+// were last sent, logging the delta.
+func changedSecrets(client *cloudagents.Client, agentId string, secrets []*livekit.AgentSecret, state *secretState) []*livekit.AgentSecret {
+	res, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: agentId,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent secrets", err)
+		exit(1)
+	}
+	deployed := make(map[string]*livekit.AgentSecret)
+	for _, secret := range res.Secrets {
+		deployed[secret.Name] = secret
+	}
+
+	var changed []*livekit.AgentSecret
+	for _, secret := range secrets {
+		current, exists := deployed[secret.Name]
+		switch {
+		case !exists:
+			log.Infow("Secret added", "secret", secret.Name)
+		case state.changed(secret, current):
+			log.Infow("Secret changed", "secret", secret.Name)
+		default:
+			log.Infow("Secret unchanged", "secret", secret.Name)
+			continue
+		}
+		changed = append(changed, secret)
+	}
+	log.Infow("Agent secrets compared",
+		"agent", agentId,
+		"changed", len(changed),
+		"unchanged", len(secrets)-len(changed),
+	)
+	return changed
+}
+
+// recordSecretState saves the hashes of the sent secrets along with their new
+// updated_at, so the next run can skip them if they did not change.
+func recordSecretState(client *cloudagents.Client, agentId string, sent []*livekit.AgentSecret, state *secretState, stateFile string) {
+	res, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: agentId,
+	})
+	if err != nil {
+		log.Warnw("Failed to list agent secrets, secrets state not saved", err)
+		return
+	}
+	deployed := make(map[string]*livekit.AgentSecret)
+	for _, secret := range res.Secrets {
+		deployed[secret.Name] = secret
+	}
+	for _, secret := range sent {
+		state.record(secret, deployed[secret.Name])
+	}
+	if err := state.save(stateFile); err != nil {
+		log.Warnw("Failed to save secrets state file", err, "path", stateFile)
+		return
+	}
+	log.Infow("Secrets state saved", "path", stateFile)
+}
+
+// scaleAgent sets the agent's replicas and maximum replicas. UpdateAgentRequest
+// has no minimum replicas field, so MIN_REPLICAS is not offered.
+func scaleAgent(client *cloudagents.Client, workingDir string, replicas int, maxReplicas int) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	// inputs take precedence over the values declared in livekit.toml
+	if replicas == 0 {
+		replicas = lkConfig.Agent.Replicas
+	}
+	if maxReplicas == 0 {
+		maxReplicas = lkConfig.Agent.MaxReplicas
+	}
+	if replicas == 0 && maxReplicas == 0 {
+		log.Errorw("REPLICAS or MAX_REPLICAS must be set", nil)
+		exit(1)
+	}
+	if err := ValidateReplicas(replicas, maxReplicas); err != nil {
+		log.Errorw("Invalid replica count", err, "replicas", replicas, "maxReplicas", maxReplicas)
+		exit(1)
+	}
+
+	resp, err := client.UpdateAgent(context.Background(), &livekit.UpdateAgentRequest{
+		AgentId:     lkConfig.Agent.ID,
+		Replicas:    int32(replicas),
+		MaxReplicas: int32(maxReplicas),
+	})
+	if err != nil {
+		log.Errorw("Failed to scale agent", err)
+		exit(1)
+	}
+	if !resp.Success {
+		log.Errorw("Failed to scale agent", errors.New(resp.Message))
+		exit(1)
+	}
+
+	log.Infow("Agent scaled", "agent", lkConfig.Agent.ID, "replicas", replicas, "maxReplicas", maxReplicas)
+}
+
+func validateConfig(workingDir string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	errs := lkConfig.Validate()
+	for _, err := range errs {
+		log.Errorw("Invalid livekit.toml", err)
+	}
+	if len(errs) > 0 {
+		exit(1)
+	}
+
+	log.Infow("livekit.toml is valid", "path", filepath.Join(workingDir, LiveKitTOMLFile))
+}
+
+// planDeploy prints what a deploy would change without mutating the agent.
+func planDeploy(client *cloudagents.Client, secrets []*livekit.AgentSecret, workingDir string, secretsMode string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to get agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Agent not found", nil, "agent", lkConfig.Agent.ID)
+		exit(1)
+	}
+	agent := res.Agents[0]
+
+	secretsRes, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent secrets", err)
+		exit(1)
+	}
+
+	hash, err := sourceArchiveHash(newSourceFS(workingDir), sourceExcludes(workingDir))
+	if err != nil {
+		log.Errorw("Failed to hash agent source", err)
+		exit(1)
+	}
+
+	fmt.Printf("Plan for agent %s\n\n", agent.AgentId)
+	fmt.Printf("Source:\n%s\n", planSource(workingDir, hash, agent.Version))
+
+	fmt.Println("Secrets:")
+	added, removed, unchanged := diffSecretNames(secrets, secretsRes.Secrets)
+	for _, name := range added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range unchanged {
+		fmt.Printf("  ~ %s\n", name)
+	}
+	// the deploy only replaces the secrets when it carries some, and merge
+	// keeps the ones it doesn't carry
+	for _, name := range removed {
+		if secretsMode == "merge" || len(secrets) == 0 {
+			fmt.Printf("    %s (not provided, kept)\n", name)
+		} else {
+			fmt.Printf("  - %s (not provided)\n", name)
+		}
+	}
+
+	if len(lkConfig.Agent.Regions) > 0 {
+		fmt.Println("\nRegions:")
+		current := make(map[string]bool)
+		for _, deployment := range agent.AgentDeployments {
+			current[deployment.Region] = true
+		}
+		desired := make(map[string]bool)
+		for _, region := range lkConfig.Agent.Regions {
+			desired[region] = true
+			if current[region] {
+				fmt.Printf("    %s\n", region)
+			} else {
+				fmt.Printf("  + %s\n", region)
+			}
+		}
+		for _, deployment := range agent.AgentDeployments {
+			if !desired[deployment.Region] {
+				fmt.Printf("  - %s\n", deployment.Region)
+			}
+		}
+	}
+}
+
+// planSource describes how the source hash compares with the hash recorded on
+// the last successful GitHub deployment of workingDir. The deployed source is
+// only known from the deployments, so without GITHUB_DEPLOYMENT, or when the
+// agent runs another version than the deployment left, it cannot be diffed.
+func planSource(workingDir string, hash string, version string) string {
+	undiffable := fmt.Sprintf("  ~ build from source %s (currently deployed version %s, its source hash is unknown so the source cannot be diffed)\n", hash, version)
+	if !getBoolInput("GITHUB_DEPLOYMENT") {
+		return undiffable
+	}
+
+	gh, err := newGitHubClient()
+	if err != nil {
+		log.Warnw("Failed to create GitHub client, the source cannot be diffed", err)
+		return undiffable
+	}
+	last, err := lastSuccessfulDeployment(context.Background(), gh, deploymentEnvironment(), filepath.ToSlash(filepath.Clean(workingDir)))
+	if err != nil {
+		log.Warnw("Failed to find the last deployment, the source cannot be diffed", err)
+		return undiffable
+	}
+	if last == nil || last.Payload.SourceHash == "" || (last.Version != "" && last.Version != version) {
+		return undiffable
+	}
+	if last.Payload.SourceHash == hash {
+		return fmt.Sprintf("    source %s unchanged (currently deployed version %s)\n", hash, version)
+	}
+	return fmt.Sprintf("  ~ source %s changed from %s (currently deployed version %s)\n", hash, last.Payload.SourceHash, version)
+}
+
+func listAgentVersions(client *cloudagents.Client, workingDir string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgentVersions(context.Background(), &livekit.ListAgentVersionsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent versions", err)
+		exit(1)
+	}
+
+	// newest first
+	versions := res.Versions
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.AsTime().After(versions[j].CreatedAt.AsTime())
+	})
+
+	for _, v := range versions {
+		log.Infow("Agent version",
+			"agent", lkConfig.Agent.ID,
+			"version", v.Version,
+			"current", v.Current,
+			"status", v.Status,
+			"createdAt", v.CreatedAt.AsTime(),
+			"deployedAt", v.DeployedAt.AsTime(),
+			"attributes", v.Attributes,
+		)
+	}
+	log.Infow("Listed agent versions", "agent", lkConfig.Agent.ID, "count", len(versions))
+}
+
+// cloneAgent creates a new agent from the working directory, copying the
+// regions and secrets of an existing agent.
+func cloneAgent(client *cloudagents.Client, subdomain string, secrets []*livekit.AgentSecret, workingDir string, sourceAgentId string) {
+	if sourceAgentId == "" {
+		log.Errorw("SOURCE_AGENT_ID is not set", nil)
+		exit(1)
+	}
+
+	if _, err := os.Stat(filepath.Join(workingDir, LiveKitTOMLFile)); err == nil {
+		log.Errorw("livekit.toml already exists", nil, "path", filepath.Join(workingDir, LiveKitTOMLFile))
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: sourceAgentId,
+	})
+	if err != nil {
+		log.Errorw("Failed to get source agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Source agent not found", nil, "agent", sourceAgentId)
+		exit(1)
+	}
+	source := res.Agents[0]
+
+	var regions []string
+	for _, deployment := range source.AgentDeployments {
+		if !slices.Contains(regions, deployment.Region) {
+			regions = append(regions, deployment.Region)
+		}
+	}
+
+	secretsRes, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: sourceAgentId,
+	})
+	if err != nil {
+		log.Errorw("Failed to list source agent secrets", err)
+		exit(1)
+	}
+
+	// provided secrets take precedence, values are only copied when the API returns them
+	cloned := slices.Clone(secrets)
+	for _, secret := range secretsRes.Secrets {
+		if slices.ContainsFunc(secrets, func(s *livekit.AgentSecret) bool { return s.Name == secret.Name }) {
+			continue
+		}
+		if len(secret.Value) == 0 {
+			log.Warnw("Secret value not available, provide it to the action to copy it", nil, "secret", secret.Name)
+			continue
+		}
+		cloned = append(cloned, &livekit.AgentSecret{
+			Name:  secret.Name,
+			Value: secret.Value,
+			Kind:  secret.Kind,
+		})
+	}
+
+	log.Infow("Cloning agent", "source", sourceAgentId, "regions", regions, "secrets", len(cloned))
+
+	startGroup(buildGroup)
+	excludes := sourceExcludes(workingDir)
+	checkUploadSize(workingDir, excludes)
+	streamBuildOutput()
+	resp, err := client.CreateAgent(
+		context.Background(),
+		newSourceFS(workingDir),
+		cloned,
+		regions,
+		excludes,
+	)
+	endBuildOutput()
+	if err != nil {
+		log.Errorw("Failed to create agent", err)
+		exit(1)
+	}
+	endGroup()
+
+	lkConfig := NewLiveKitTOML(subdomain).WithDefaultAgent()
+	lkConfig.Agent.ID = resp.AgentId
+	lkConfig.Agent.Regions = regions
+	if err := lkConfig.SaveTOMLFile(workingDir, LiveKitTOMLFile); err != nil {
+		log.Errorw("Failed to save livekit.toml", err)
+		exit(1)
+	}
+
+	log.Infow("Agent cloned", "agent", resp.AgentId, "source", sourceAgentId)
+}
+
+// updateAgentMetadata sets the agent's name. UpdateAgentRequest has no
+// description or label fields, so those cannot be set.
+func updateAgentMetadata(client *cloudagents.Client, workingDir string, name string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	if name == "" {
+		name = lkConfig.Agent.Name
+	}
+	if name == "" {
+		log.Errorw("AGENT_NAME or agent.name in livekit.toml must be set", nil)
+		exit(1)
+	}
+
+	resp, err := client.UpdateAgent(context.Background(), &livekit.UpdateAgentRequest{
+		AgentId:   lkConfig.Agent.ID,
+		AgentName: name,
+	})
+	if err != nil {
+		log.Errorw("Failed to update agent metadata", err)
+		exit(1)
+	}
+	if !resp.Success {
+		log.Errorw("Failed to update agent metadata", errors.New(resp.Message))
+		exit(1)
+	}
+
+	log.Infow("Agent metadata updated", "agent", lkConfig.Agent.ID, "name", name)
+}
+
+// reconcileRegions adds or removes regional deployments so the agent runs in
+// exactly the regions listed in livekit.toml.
+func reconcileRegions(client *cloudagents.Client, workingDir string, dryRun bool) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	desired := lkConfig.Agent.Regions
+	if len(desired) == 0 {
+		log.Errorw("agent.regions in livekit.toml must list at least one region", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to get agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Agent not found", nil, "agent", lkConfig.Agent.ID)
+		exit(1)
+	}
+
+	var current []string
+	for _, deployment := range res.Agents[0].AgentDeployments {
+		if !slices.Contains(current, deployment.Region) {
+			current = append(current, deployment.Region)
+		}
+	}
+
+	var added, removed []string
+	for _, region := range desired {
+		if !slices.Contains(current, region) {
+			added = append(added, region)
+		}
+	}
+	for _, region := range current {
+		if !slices.Contains(desired, region) {
+			removed = append(removed, region)
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		log.Infow("Agent regions are up to date", "agent", lkConfig.Agent.ID, "regions", current)
+		return
+	}
+
+	log.Infow("Agent regions differ", "agent", lkConfig.Agent.ID, "add", added, "remove", removed)
+	if dryRun {
+		log.Infow("Dry run, agent regions not updated")
+		return
+	}
+
+	resp, err := client.UpdateAgent(context.Background(), &livekit.UpdateAgentRequest{
+		AgentId: lkConfig.Agent.ID,
+		Regions: desired,
+	})
+	if err != nil {
+		log.Errorw("Failed to update agent regions", err)
+		exit(1)
+	}
+	if !resp.Success {
+		log.Errorw("Failed to update agent regions", errors.New(resp.Message))
+		exit(1)
+	}
+
+	log.Infow("Agent regions updated", "agent", lkConfig.Agent.ID, "regions", desired)
+}
+
+// listAgentSecrets logs the names of the secrets attached to the agent. Values
+// are never printed.
+func listAgentSecrets(client *cloudagents.Client, workingDir string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent secrets", err)
+		exit(1)
+	}
+
+	for _, secret := range res.Secrets {
+		log.Infow("Agent secret",
+			"agent", lkConfig.Agent.ID,
+			"secret", secret.Name,
+			"kind", secret.Kind.String(),
+			"updatedAt", secret.UpdatedAt.AsTime(),
+		)
+	}
+	log.Infow("Listed agent secrets", "agent", lkConfig.Agent.ID, "count", len(res.Secrets))
+}
+
+type agentExport struct {
+	AgentID     string             `json:"agent_id" toml:"agent_id"`
+	AgentName   string             `json:"agent_name,omitempty" toml:"agent_name,omitempty"`
+	Version     string             `json:"version" toml:"version"`
+	DeployedAt  time.Time          `json:"deployed_at" toml:"deployed_at"`
+	Deployments []deploymentExport `json:"deployments" toml:"deployments"`
+	Secrets     []string           `json:"secrets" toml:"secrets"`
+}
+
+type deploymentExport struct {
+	Region      string `json:"region" toml:"region"`
+	Status      string `json:"status" toml:"status"`
+	Replicas    int32  `json:"replicas" toml:"replicas"`
+	MinReplicas int32  `json:"min_replicas" toml:"min_replicas"`
+	MaxReplicas int32  `json:"max_replicas" toml:"max_replicas"`
+	CPURequest  string `json:"cpu_req,omitempty" toml:"cpu_req,omitempty"`
+	MemRequest  string `json:"mem_req,omitempty" toml:"mem_req,omitempty"`
+}
+
+// exportAgent writes the cloud-side agent configuration to a JSON file, or a
+// TOML file when the path ends in .toml. Secret values are never exported.
+func exportAgent(client *cloudagents.Client, workingDir string, exportFile string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	if exportFile == "" {
+		exportFile = filepath.Join(workingDir, "agent-export.json")
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to get agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Agent not found", nil, "agent", lkConfig.Agent.ID)
+		exit(1)
+	}
+	agent := res.Agents[0]
+
+	secretsRes, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent secrets", err)
+		exit(1)
+	}
+
+	export := agentExport{
+		AgentID:    agent.AgentId,
+		AgentName:  agent.AgentName,
+		Version:    agent.Version,
+		DeployedAt: agent.DeployedAt.AsTime(),
+		Secrets:    []string{},
+	}
+	for _, d := range agent.AgentDeployments {
+		export.Deployments = append(export.Deployments, deploymentExport{
+			Region:      d.Region,
+			Status:      d.Status,
+			Replicas:    d.Replicas,
+			MinReplicas: d.MinReplicas,
+			MaxReplicas: d.MaxReplicas,
+			CPURequest:  d.CpuReq,
+			MemRequest:  d.MemReq,
+		})
+	}
+	for _, secret := range secretsRes.Secrets {
+		export.Secrets = append(export.Secrets, secret.Name)
+	}
+
+	var data []byte
+	if strings.HasSuffix(exportFile, ".toml") {
+		var buf bytes.Buffer
+		err = toml.NewEncoder(&buf).Encode(export)
+		data = buf.Bytes()
+	} else {
+		data, err = json.MarshalIndent(export, "", "  ")
+	}
+	if err != nil {
+		log.Errorw("Failed to encode agent export", err)
+		exit(1)
+	}
+
+	if err := os.WriteFile(exportFile, data, 0644); err != nil {
+		log.Errorw("Failed to write agent export", err, "path", exportFile)
+		exit(1)
+	}
+
+	log.Infow("Agent exported", "agent", agent.AgentId, "path", exportFile)
+}
+
+// adoptAgent writes a livekit.toml for an agent that already exists, so that
+// agents created outside of this action can be deployed from CI.
+func adoptAgent(client *cloudagents.Client, subdomain string, workingDir string, agentId string, agentName string) {
+	if agentId == "" && agentName == "" {
+		log.Errorw("AGENT_ID or AGENT_NAME must be set", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId:   agentId,
+		AgentName: agentName,
+	})
+	if err != nil {
+		log.Errorw("Failed to get agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Agent not found", nil, "agent", agentId, "name", agentName)
+		exit(1)
+	}
+	if len(res.Agents) > 1 {
+		log.Errorw("Multiple agents found, use AGENT_ID instead", nil, "name", agentName, "count", len(res.Agents))
+		exit(1)
+	}
+	agent := res.Agents[0]
+
+	existing, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+	if exists {
+		if existing.HasAgent() && existing.Agent.ID == agent.AgentId {
+			log.Infow("livekit.toml already references agent", "agent", agent.AgentId)
+			return
+		}
+		log.Errorw("livekit.toml already exists for a different agent", nil, "path", filepath.Join(workingDir, LiveKitTOMLFile))
+		exit(1)
+	}
+
+	lkConfig := NewLiveKitTOML(subdomain).WithDefaultAgent()
+	lkConfig.Agent.ID = agent.AgentId
+	lkConfig.Agent.Name = agent.AgentName
+	for _, deployment := range agent.AgentDeployments {
+		if !slices.Contains(lkConfig.Agent.Regions, deployment.Region) {
+			lkConfig.Agent.Regions = append(lkConfig.Agent.Regions, deployment.Region)
+		}
+	}
+	if err := lkConfig.SaveTOMLFile(workingDir, LiveKitTOMLFile); err != nil {
+		log.Errorw("Failed to save livekit.toml", err)
+		exit(1)
+	}
+
+	log.Infow("Agent adopted", "agent", agent.AgentId, "path", filepath.Join(workingDir, LiveKitTOMLFile))
+}
+
+// agentHealth evaluates the agent against the configured health criteria and
+// fails with a report of every violation. AgentDeployment reports no restart
+// counts, so they cannot be checked.
+func agentHealth(client *cloudagents.Client, workingDir string, minReplicas int, maxDeployAge time.Duration) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to get agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Agent not found", nil, "agent", lkConfig.Agent.ID)
+		exit(1)
+	}
+	agent := res.Agents[0]
+
+	var violations []string
+	if len(agent.AgentDeployments) == 0 {
+		violations = append(violations, "agent has no regional deployments")
+	}
+	for _, d := range agent.AgentDeployments {
+		if d.Status != "Running" {
+			violations = append(violations, fmt.Sprintf("region %s is %s", d.Region, d.Status))
+		}
+		if int(d.Replicas) < minReplicas {
+			violations = append(violations, fmt.Sprintf("region %s has %d replicas, expected at least %d", d.Region, d.Replicas, minReplicas))
+		}
+		log.Infow("Agent deployment health", "agent", agent.AgentId, "region", d.Region, "status", d.Status, "replicas", d.Replicas)
+	}
+	if maxDeployAge > 0 {
+		if age := time.Since(agent.DeployedAt.AsTime()); age > maxDeployAge {
+			violations = append(violations, fmt.Sprintf("last deploy was %s ago, expected within %s", age.Round(time.Minute), maxDeployAge))
+		}
+	}
+
+	if len(violations) > 0 {
+		for _, v := range violations {
+			log.Errorw("Agent health check failed", nil, "agent", agent.AgentId, "violation", v)
+		}
+		alertAgent(client, agent, agent.AgentId, fmt.Sprintf("Agent %s is unhealthy:\n- %s", agent.AgentId, strings.Join(violations, "\n- ")))
+		exit(1)
+	}
+
+	log.Infow("Agent is healthy", "agent", agent.AgentId, "version", agent.Version)
+	agentRecovered(agent, agent.AgentId)
+}
+
+type deploymentMetrics struct {
+	Region      string `json:"region"`
+	Status      string `json:"status"`
+	Replicas    int32  `json:"replicas"`
+	MinReplicas int32  `json:"min_replicas"`
+	MaxReplicas int32  `json:"max_replicas"`
+	CPU         string `json:"cpu"`
+	CPURequest  string `json:"cpu_req"`
+	CPULimit    string `json:"cpu_limit"`
+	Memory      string `json:"mem"`
+	MemRequest  string `json:"mem_req"`
+	MemLimit    string `json:"mem_limit"`
+}
+
+// agentMetrics logs the resource usage of every regional deployment, and
+// writes it as JSON when a metrics file is set. AgentDeployment reports no job
+// or worker counts, so they are not included.
+func agentMetrics(client *cloudagents.Client, workingDir string, metricsFile string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to get agent", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Errorw("Agent not found", nil, "agent", lkConfig.Agent.ID)
+		exit(1)
+	}
+
+	metrics := make([]deploymentMetrics, 0)
+	for _, d := range res.Agents[0].AgentDeployments {
+		m := deploymentMetrics{
+			Region:      d.Region,
+			Status:      d.Status,
+			Replicas:    d.Replicas,
+			MinReplicas: d.MinReplicas,
+			MaxReplicas: d.MaxReplicas,
+			CPU:         d.CurCpu,
+			CPURequest:  d.CpuReq,
+			CPULimit:    d.CpuLimit,
+			Memory:      d.CurMem,
+			MemRequest:  d.MemReq,
+			MemLimit:    d.MemLimit,
+		}
+		metrics = append(metrics, m)
+		log.Infow("Agent metrics",
+			"agent", lkConfig.Agent.ID,
+			"region", m.Region,
+			"replicas", m.Replicas,
+			"cpu", m.CPU,
+			"cpuLimit", m.CPULimit,
+			"mem", m.Memory,
+			"memLimit", m.MemLimit,
+		)
+	}
+
+	if metricsFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		log.Errorw("Failed to encode agent metrics", err)
+		exit(1)
+	}
+	if err := os.WriteFile(metricsFile, data, 0644); err != nil {
+		log.Errorw("Failed to write agent metrics", err, "path", metricsFile)
+		exit(1)
+	}
+	log.Infow("Agent metrics written", "path", metricsFile)
+}
+
+// diffSecretNames compares provided secrets with the ones on the agent by name.
+// Values are never compared, as the API does not return them.
+func diffSecretNames(provided []*livekit.AgentSecret, deployed []*livekit.AgentSecret) (added, removed, unchanged []string) {
+	deployedNames := make(map[string]bool)
+	for _, secret := range deployed {
+		deployedNames[secret.Name] = true
+	}
+	providedNames := make(map[string]bool)
+	for _, secret := range provided {
+		if providedNames[secret.Name] {
+			continue
+		}
+		providedNames[secret.Name] = true
+		if deployedNames[secret.Name] {
+			unchanged = append(unchanged, secret.Name)
+		} else {
+			added = append(added, secret.Name)
+		}
+	}
+	for _, secret := range deployed {
+		if !providedNames[secret.Name] {
+			removed = append(removed, secret.Name)
+		}
+	}
+	return added, removed, unchanged
+}
+
+func diffAgentSecrets(client *cloudagents.Client, secrets []*livekit.AgentSecret, workingDir string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	if !exists {
+		log.Errorw("livekit.toml not found", nil)
+		exit(1)
+	}
+
+	res, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agent secrets", err)
+		exit(1)
+	}
+
+	added, removed, unchanged := diffSecretNames(secrets, res.Secrets)
+	for _, name := range added {
+		log.Infow("Secret added", "secret", name)
+	}
+	for _, name := range removed {
+		log.Infow("Secret removed", "secret", name)
+	}
+	for _, name := range unchanged {
+		log.Infow("Secret unchanged", "secret", name)
+	}
+	log.Infow("Agent secrets compared",
+		"agent", lkConfig.Agent.ID,
+		"added", len(added),
+		"removed", len(removed),
+		"unchanged", len(unchanged),
+	)
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// deploymentManifest records what was deployed, for archival or attestation.
+// It never contains secret values.
+type deploymentManifest struct {
+	Operation    string    `json:"operation"`
+	Success      bool      `json:"success"`
+	AgentID      string    `json:"agent_id,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	Release      string    `json:"release,omitempty"`
+	Environment  string    `json:"environment,omitempty"`
+	Regions      []string  `json:"regions,omitempty"`
+	Repository   string    `json:"repository,omitempty"`
+	Commit       string    `json:"commit,omitempty"`
+	Ref          string    `json:"ref,omitempty"`
+	RunID        string    `json:"run_id,omitempty"`
+	SourceSHA256 string    `json:"source_sha256,omitempty"`
+	Secrets      []string  `json:"secrets"`
+	StartedAt    time.Time `json:"started_at"`
+	CompletedAt  time.Time `json:"completed_at"`
+	DeployedAt   time.Time `json:"deployed_at,omitzero"`
+}
+
+func newDeploymentManifest(report *operationReport, workingDir string, secrets []*livekit.AgentSecret, startTime time.Time) (*deploymentManifest, error) {
+	hash, err := sourceHash(os.DirFS(workingDir), sourceExcludes(workingDir))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &deploymentManifest{
+		Operation:    report.Operation,
+		Success:      report.Code == 0,
+		AgentID:      report.AgentID,
+		Release:      report.Release,
+		Environment:  report.Environment,
+		Repository:   os.Getenv("GITHUB_REPOSITORY"),
+		Commit:       commitSHA(),
+		Ref:          os.Getenv("GITHUB_REF"),
+		RunID:        os.Getenv("GITHUB_RUN_ID"),
+		SourceSHA256: hash,
+		Secrets:      []string{},
+		StartedAt:    startTime.UTC(),
+		CompletedAt:  time.Now().UTC(),
+	}
+	for _, secret := range secrets {
+		m.Secrets = append(m.Secrets, secret.Name)
+	}
+	slices.Sort(m.Secrets)
+
+	if agent := report.Agent; agent != nil {
+		m.Version = agent.Version
+		for _, deployment := range agent.AgentDeployments {
+			if !slices.Contains(m.Regions, deployment.Region) {
+				m.Regions = append(m.Regions, deployment.Region)
+			}
+		}
+		if agent.DeployedAt != nil {
+			m.DeployedAt = agent.DeployedAt.AsTime().UTC()
+		}
+	}
+	return m, nil
+}
+
+func (m *deploymentManifest) write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,518 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/slack-go/slack"
+)
+
+// Notification events, available to templates as .Event.
+const (
+	eventStarted   = "started"
+	eventProgress  = "progress"
+	eventSucceeded = "succeeded"
+	eventFailed    = "failed"
+	eventAlert     = "alert"
+	eventRecovered = "recovered"
+)
+
+// notification is an event to tell the team about.
+type notification struct {
+	Event   string
+	Message string
+	// Agent is nil when it does not exist yet or could not be fetched, AgentID
+	// is used then.
+	Agent    *livekit.AgentInfo
+	AgentID  string
+	Duration time.Duration
+	// Log is the end of the build log for failures, or of the runtime log for
+	// alerts, to triage from.
+	Log []string
+}
+
+// notificationTemplate renders notification bodies, from NOTIFICATION_TEMPLATE.
+var notificationTemplate *template.Template
+
+func loadNotificationTemplate() {
+	text := os.Getenv("INPUT_NOTIFICATION_TEMPLATE")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		log.Errorw("Invalid NOTIFICATION_TEMPLATE", err)
+		exit(1)
+	}
+	notificationTemplate = tmpl
+}
+
+// notificationData is what notification templates render.
+type notificationData struct {
+	Event       string
+	Message     string
+	Operation   string
+	Environment string
+	Release     string
+	Agent       notificationAgent
+	Git         notificationGit
+	Duration    time.Duration
+	StartedAt   time.Time
+	// Phases are the log groups finished so far, with how long each took.
+	Phases  []notificationPhase
+	Changes notificationChanges
+	Links   notificationLinks
+	// Log are the last NOTIFY_LOG_LINES lines of the build log for failures,
+	// or of the runtime log for alerts.
+	Log []string
+}
+
+type notificationPhase struct {
+	Name     string
+	Duration time.Duration
+}
+
+type notificationAgent struct {
+	ID      string
+	Version string
+	Status  string
+	Regions []notificationRegion
+}
+
+type notificationRegion struct {
+	Region      string
+	Status      string
+	Replicas    int32
+	MaxReplicas int32
+}
+
+type notificationGit struct {
+	Repository  string
+	Commit      string
+	ShortCommit string
+	CommitURL   string
+	Ref         string
+	Actor       string
+	RunURL      string
+}
+
+func (n notification) data() notificationData {
+	d := notificationData{
+		Event:       n.Event,
+		Message:     n.Message,
+		Operation:   result.Operation,
+		Environment: environmentName(),
+		Release:     releaseVersion(),
+		Agent:       notificationAgent{ID: n.AgentID},
+		Git: notificationGit{
+			Repository: os.Getenv("GITHUB_REPOSITORY"),
+			Commit:     commitSHA(),
+			Ref:        os.Getenv("GITHUB_REF"),
+			Actor:      os.Getenv("GITHUB_ACTOR"),
+			RunURL:     workflowRunURL(),
+		},
+		Duration:  n.Duration.Round(time.Second),
+		StartedAt: result.StartedAt,
+		Log:       n.Log,
+	}
+	for _, p := range phases {
+		d.Phases = append(d.Phases, notificationPhase{
+			Name:     p.Name,
+			Duration: time.Duration(p.DurationSeconds * float64(time.Second)).Round(time.Second),
+		})
+	}
+	if n.Agent != nil {
+		d.Agent.ID = n.Agent.AgentId
+		d.Agent.Version = n.Agent.Version
+		d.Agent.Status = agentOverallStatus(n.Agent)
+		for _, deployment := range n.Agent.AgentDeployments {
+			d.Agent.Regions = append(d.Agent.Regions, notificationRegion{
+				Region:      deployment.Region,
+				Status:      deployment.Status,
+				Replicas:    deployment.Replicas,
+				MaxReplicas: deployment.MaxReplicas,
+			})
+		}
+	}
+	d.Git.ShortCommit = shortCommit(d.Git.Commit)
+	if server := os.Getenv("GITHUB_SERVER_URL"); server != "" && d.Git.Repository != "" && d.Git.Commit != "" {
+		d.Git.CommitURL = fmt.Sprintf("%s/%s/commit/%s", server, d.Git.Repository, d.Git.Commit)
+	}
+	d.Changes = changesFor(n.Agent, d.Git.Commit)
+	d.Links = notificationLinks{
+		Dashboard: liveKitCloudURL,
+		Compare:   d.Changes.CompareURL,
+		Commit:    d.Git.CommitURL,
+		Run:       d.Git.RunURL,
+	}
+	return d
+}
+
+// text is the body of the notification: NOTIFICATION_TEMPLATE rendered with
+// its data, or the message prefixed with the environment. A template that
+// fails to render falls back to the message.
+func (n notification) text() string {
+	if notificationTemplate != nil {
+		var text strings.Builder
+		err := notificationTemplate.Execute(&text, n.data())
+		if err == nil {
+			return strings.TrimSpace(text.String())
+		}
+		log.Warnw("Failed to render NOTIFICATION_TEMPLATE", err)
+	}
+	if environment := environmentName(); environment != "" {
+		return fmt.Sprintf("[%s] %s", environment, n.Message)
+	}
+	return n.Message
+}
+
+// notifier delivers notifications to one channel.
+type notifier interface {
+	// name identifies the notifier in logs, and its <NAME>_EVENTS filter.
+	name() string
+	// configured reports whether the inputs the notifier needs are set.
+	configured() bool
+	// supports reports whether the channel can take the event at all.
+	supports(event string) bool
+	send(n notification) error
+}
+
+// notifiers are every channel notifications can be sent to. Each configured
+// one gets the notifications its filter allows, so several can be used at once.
+var notifiers = []notifier{
+	slackNotifier{},
+	discordNotifier{},
+	teamsNotifier{},
+	telegramNotifier{},
+	googleChatNotifier{},
+	webhookNotifier{},
+	pagerDutyNotifier{},
+}
+
+var (
+	// notifyRetries is how many times a failed delivery is retried.
+	notifyRetries = 2
+	// notifyFailurePolicy is warn to log delivery failures, or fail to also
+	// fail the action.
+	notifyFailurePolicy = "warn"
+	// notifyLogLines is how many log lines failures and alerts include, from
+	// NOTIFY_LOG_LINES.
+	notifyLogLines = 20
+	// notificationFailed is set when a delivery failed under the fail policy
+	// while the action was exiting, so it exits with an error.
+	notificationFailed bool
+)
+
+// notifyOn are the only events notified on, from NOTIFY_ON, applied across
+// every notifier. nil allows every event.
+var notifyOn map[string]bool
+
+// notifierEvents are the events each notifier is limited to, from its
+// <NAME>_EVENTS input. Notifiers without a filter get every event.
+var notifierEvents = make(map[string]map[string]bool)
+
+// loadNotifierConfig reads the notifier filters and the delivery policy.
+func loadNotifierConfig() {
+	if os.Getenv("INPUT_NOTIFY_RETRIES") != "" {
+		notifyRetries = getIntInput("NOTIFY_RETRIES")
+	}
+	if os.Getenv("INPUT_NOTIFY_LOG_LINES") != "" {
+		notifyLogLines = getIntInput("NOTIFY_LOG_LINES")
+	}
+	switch policy := os.Getenv("INPUT_NOTIFY_FAILURE_POLICY"); policy {
+	case "":
+	case "warn", "fail":
+		notifyFailurePolicy = policy
+	default:
+		log.Errorw("Invalid NOTIFY_FAILURE_POLICY, expected warn or fail", nil, "value", policy)
+		exit(1)
+	}
+
+	switch levels := getListInput("NOTIFY_ON"); {
+	case len(levels) == 0 || len(levels) == 1 && levels[0] == "always":
+	case len(levels) == 1 && levels[0] == "never":
+		notifyOn = map[string]bool{}
+	case len(levels) == 1 && levels[0] == "failure":
+		notifyOn = map[string]bool{eventFailed: true, eventAlert: true, eventRecovered: true}
+	case len(levels) == 1 && levels[0] == "success":
+		notifyOn = map[string]bool{eventSucceeded: true}
+	default:
+		notifyOn = make(map[string]bool)
+		for _, event := range levels {
+			switch event {
+			case eventStarted, eventProgress, eventSucceeded, eventFailed, eventAlert, eventRecovered:
+				notifyOn[event] = true
+			default:
+				log.Errorw("Invalid NOTIFY_ON, expected always, failure, success, never or a list of started, progress, succeeded, failed, alert and recovered", nil, "value", event)
+				exit(1)
+			}
+		}
+	}
+
+	for _, nt := range notifiers {
+		input := strings.ToUpper(nt.name()) + "_EVENTS"
+		events := getListInput(input)
+		if len(events) == 0 {
+			continue
+		}
+		filter := make(map[string]bool)
+		for _, event := range events {
+			switch event {
+			case eventStarted, eventProgress, eventSucceeded, eventFailed, eventAlert, eventRecovered:
+				filter[event] = true
+			default:
+				log.Errorw("Invalid "+input+", expected started, progress, succeeded, failed, alert or recovered", nil, "value", event)
+				exit(1)
+			}
+		}
+		notifierEvents[nt.name()] = filter
+	}
+}
+
+// notify sends the notification to every configured notifier that takes the
+// event. Failed deliveries are retried with backoff, and then handled as
+// NOTIFY_FAILURE_POLICY says. Events NOTIFY_ON leaves out are dropped.
+func notify(n notification) {
+	if notifyOn != nil && !notifyOn[n.Event] {
+		return
+	}
+	failed := false
+	for _, nt := range notifiers {
+		if !nt.configured() || !nt.supports(n.Event) {
+			continue
+		}
+		if filter, ok := notifierEvents[nt.name()]; ok && !filter[n.Event] {
+			continue
+		}
+		if err := deliver(nt, n); err != nil {
+			failed = true
+			if notifyFailurePolicy == "fail" {
+				log.Errorw("Failed to send notification", err, "notifier", nt.name(), "event", n.Event)
+			} else {
+				log.Warnw("Failed to send notification", err, "notifier", nt.name(), "event", n.Event)
+			}
+			continue
+		}
+		log.Infow("Notification sent", "notifier", nt.name(), "event", n.Event)
+	}
+
+	if failed && notifyFailurePolicy == "fail" {
+		// exit hooks cannot exit again, the exit code is changed instead
+		if exiting {
+			notificationFailed = true
+			return
+		}
+		exit(1)
+	}
+}
+
+// deliver sends the notification, retrying failures that may be temporary
+// after 1s, 2s, 4s and so on.
+func deliver(nt notifier, n notification) error {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err := nt.send(n)
+		if err == nil || attempt >= notifyRetries || !retryableNotificationError(err) {
+			return err
+		}
+		log.Infow("Retrying notification", "notifier", nt.name(), "event", n.Event, "error", err, "in", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postWebhook posts the payload as JSON to a notifier's webhook. With a
+// secret, the body is signed with HMAC-SHA256 in the X-LiveKit-Signature-256
+// header, as sha256=<hex>.
+func postWebhook(url string, payload any, secret string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		req.Header.Set("X-LiveKit-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return &webhookStatusError{status: resp.Status, StatusCode: resp.StatusCode, body: body}
+	}
+	return nil
+}
+
+// webhookStatusError is returned by postWebhook for responses outside of 2xx.
+type webhookStatusError struct {
+	status     string
+	StatusCode int
+	body       []byte
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.status, e.body)
+}
+
+// Retryable reports whether the request may succeed if sent again, which
+// rejected requests will not.
+func (e *webhookStatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// retryableNotificationError reports whether a failed delivery is worth
+// retrying: errors that say so, like rate limits and server errors, and
+// network errors. Errors the service returned for the request are not.
+func retryableNotificationError(err error) bool {
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	var slackErr slack.SlackErrorResponse
+	return !errors.As(err, &slackErr)
+}
+
+// deployNotifyEvents returns the events to notify on for deploys, from
+// NOTIFY_EVENTS, the configured notifiers' filters, SLACK_NOTIFY_SUCCESS and
+// SLACK_THREAD, which needs the started message to thread under. Alerts are
+// always sent.
+func deployNotifyEvents() map[string]bool {
+	events := make(map[string]bool)
+	for _, event := range getListInput("NOTIFY_EVENTS") {
+		switch event {
+		case eventStarted, eventSucceeded, eventFailed:
+			events[event] = true
+		default:
+			log.Errorw("Invalid NOTIFY_EVENTS, expected started, succeeded or failed", nil, "value", event)
+			exit(1)
+		}
+	}
+	for _, nt := range notifiers {
+		if !nt.configured() {
+			continue
+		}
+		for event := range notifierEvents[nt.name()] {
+			switch event {
+			case eventStarted, eventSucceeded, eventFailed:
+				events[event] = true
+			}
+		}
+	}
+	if getBoolInput("SLACK_NOTIFY_SUCCESS") {
+		events[eventSucceeded] = true
+	}
+	if getBoolInput("SLACK_THREAD") {
+		slackThread = true
+		events[eventStarted] = true
+		events[eventSucceeded] = true
+		events[eventFailed] = true
+	}
+	return events
+}
+
+// notifyStarted tells the team the operation started, naming the agent when
+// livekit.toml already has one.
+func notifyStarted(operation string, workingDir string) {
+	target := workingDir
+	var agentID string
+	if lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile); err == nil && exists && lkConfig.HasAgent() && lkConfig.Agent.ID != "" {
+		agentID = lkConfig.Agent.ID
+		target = agentID
+	}
+	message := fmt.Sprintf("Agent %s %s started", target, operation)
+	if version := releaseVersion(); version != "" {
+		message += " for release " + version
+	}
+	notify(notification{Event: eventStarted, Message: message, AgentID: agentID})
+}
+
+// phaseSummary lists how long each phase took, for finished operations.
+func (d notificationData) phaseSummary() string {
+	if d.Event != eventSucceeded && d.Event != eventFailed {
+		return ""
+	}
+	var lines []string
+	for _, p := range d.Phases {
+		lines = append(lines, fmt.Sprintf("• %s: %s", p.Name, p.Duration))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// logExcerpt is the notification's log as one block, keeping the end of it
+// when it is longer than limit.
+func (d notificationData) logExcerpt(limit int) string {
+	excerpt := strings.Join(d.Log, "\n")
+	if runes := []rune(excerpt); len(runes) > limit {
+		excerpt = "…" + string(runes[len(runes)-limit+1:])
+	}
+	return excerpt
+}
+
+// successNotification describes a successful deploy for the release channel.
+func successNotification(report *operationReport) notification {
+	message := fmt.Sprintf("Agent %s %s succeeded", report.AgentID, report.Operation)
+	if report.Agent != nil && report.Agent.Version != "" {
+		message += fmt.Sprintf(", version %s", report.Agent.Version)
+	}
+	if report.Release != "" {
+		message += fmt.Sprintf(" of release %s", report.Release)
+	}
+	message += fmt.Sprintf(", in %s", report.Duration.Round(time.Second))
+	return notification{Event: eventSucceeded, Message: message, Agent: report.Agent, AgentID: report.AgentID, Duration: report.Duration}
+}
+
+// failureNotification describes a failed deploy with the error that ended it,
+// and the end of the build log when it failed building.
+func failureNotification(report *operationReport, buildLog []string) notification {
+	message := fmt.Sprintf("%s failed after %s", report.Operation, report.Duration.Round(time.Second))
+	if report.AgentID != "" {
+		message = fmt.Sprintf("Agent %s %s", report.AgentID, message)
+	}
+	loggedMu.Lock()
+	defer loggedMu.Unlock()
+	if lastError != "" {
+		message += ": " + lastError
+	}
+	if len(buildLog) > notifyLogLines {
+		buildLog = buildLog[len(buildLog)-notifyLogLines:]
+	}
+	return notification{Event: eventFailed, Message: message, Agent: report.Agent, AgentID: report.AgentID, Duration: report.Duration, Log: buildLog}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+// agentOverallStatus is Running when every deployment is, and otherwise the
+// status of the first deployment that is not.
+func agentOverallStatus(agent *livekit.AgentInfo) string {
+	if len(agent.AgentDeployments) == 0 {
+		return ""
+	}
+	for _, deployment := range agent.AgentDeployments {
+		if deployment.Status != "Running" {
+			return deployment.Status
+		}
+	}
+	return "Running"
+}
+
+// writeAgentOutputs sets the agent_id, deployment_id, version, status, regions,
+// release_version and environment step outputs from the live agent described
+// by livekit.toml. Output
+// failures are logged but do not fail the operation.
+func writeAgentOutputs(client *cloudagents.Client, workingDir string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil || !exists || !lkConfig.HasAgent() {
+		return
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentId: lkConfig.Agent.ID,
+	})
+	if err != nil {
+		log.Warnw("Failed to get agent for outputs", err)
+		return
+	}
+	if len(res.Agents) == 0 {
+		log.Warnw("Failed to get agent for outputs", nil, "agent", lkConfig.Agent.ID)
+		return
+	}
+	agent := res.Agents[0]
+
+	var regions []string
+	for _, deployment := range agent.AgentDeployments {
+		regions = append(regions, deployment.Region)
+	}
+
+	outputs := []struct{ name, value string }{
+		{"agent_id", agent.AgentId},
+		// each deploy creates a new version, which identifies the deployment
+		{"deployment_id", agent.AgentId + "@" + agent.Version},
+		{"version", agent.Version},
+		{"status", agentOverallStatus(agent)},
+		{"regions", strings.Join(regions, ",")},
+		{"release_version", releaseVersion()},
+		{"environment", environmentName()},
+	}
+	for _, output := range outputs {
+		if err := setOutput(output.name, output.value); err != nil {
+			log.Warnw("Failed to write output", err, "output", output.name)
+			return
+		}
+	}
+}
+
+var outputPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// writeNamespacedOutputs writes every output set under the prefix, as the
+// outputs output and as <prefix>.json in dir, so matrix legs don't collide and
+// a fan-in job can collect them from artifacts.
+func writeNamespacedOutputs(prefix string, dir string) {
+	outputs := make(map[string]string, len(stepOutputs))
+	for name, value := range stepOutputs {
+		outputs[name] = value
+	}
+
+	namespaced, err := json.Marshal(map[string]map[string]string{prefix: outputs})
+	if err != nil {
+		log.Warnw("Failed to encode outputs", err)
+		return
+	}
+	if err := setOutput("outputs", string(namespaced)); err != nil {
+		log.Warnw("Failed to write output", err, "output", "outputs")
+	}
+
+	if dir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		log.Warnw("Failed to encode outputs", err)
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Warnw("Failed to write outputs file", err, "path", dir)
+		return
+	}
+	path := filepath.Join(dir, prefix+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		log.Warnw("Failed to write outputs file", err, "path", path)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutyAlerts is set for the status and health operations when
+// PAGERDUTY_ROUTING_KEY is, so scheduled checks page on-call.
+var pagerDutyAlerts bool
+
+// pagerDutyDedupKey groups every alert for the agent into one incident.
+func pagerDutyDedupKey(agentID string) string {
+	return "livekit-cloud-agent/" + agentID
+}
+
+// pagerDutyNotifier triggers an Events API v2 alert for the agent an alert
+// is about. Other events are not sent, on-call is only paged for alerts.
+type pagerDutyNotifier struct{}
+
+func (pagerDutyNotifier) name() string { return "pagerduty" }
+
+func (pagerDutyNotifier) configured() bool { return pagerDutyAlerts }
+
+func (pagerDutyNotifier) supports(event string) bool { return event == eventAlert }
+
+func (pagerDutyNotifier) send(n notification) error {
+	d := n.data()
+
+	severity := strings.ToLower(os.Getenv("INPUT_PAGERDUTY_SEVERITY"))
+	if severity == "" {
+		severity = "critical"
+	}
+	source := d.Git.Repository
+	if source == "" {
+		source = "livekit-cloud"
+	}
+	details := map[string]any{
+		"agent_id":    d.Agent.ID,
+		"version":     d.Agent.Version,
+		"status":      d.Agent.Status,
+		"regions":     d.Agent.Regions,
+		"operation":   d.Operation,
+		"environment": d.Environment,
+		"commit":      d.Git.Commit,
+	}
+	event := map[string]any{
+		"routing_key":  getInputOrEnv("PAGERDUTY_ROUTING_KEY"),
+		"event_action": "trigger",
+		"dedup_key":    pagerDutyDedupKey(d.Agent.ID),
+		"payload": map[string]any{
+			"summary":        truncate(n.text(), 1024),
+			"source":         source,
+			"severity":       severity,
+			"component":      d.Agent.ID,
+			"group":          d.Environment,
+			"class":          d.Operation,
+			"custom_details": details,
+		},
+	}
+	if d.Git.RunURL != "" {
+		event["links"] = []map[string]string{{"href": d.Git.RunURL, "text": "Workflow run"}}
+	}
+	return postWebhook(pagerDutyEventsURL, event, "")
+}
+
+// resolvePagerDutyAlert resolves the agent's incident once a check passes
+// again. Resolving an agent without an open incident does nothing.
+func resolvePagerDutyAlert(agentID string) {
+	if !pagerDutyAlerts {
+		return
+	}
+	event := map[string]any{
+		"routing_key":  getInputOrEnv("PAGERDUTY_ROUTING_KEY"),
+		"event_action": "resolve",
+		"dedup_key":    pagerDutyDedupKey(agentID),
+	}
+	if err := postWebhook(pagerDutyEventsURL, event, ""); err != nil {
+		log.Warnw("Failed to resolve PagerDuty alert", err)
+		return
+	}
+	log.Infow("PagerDuty alert resolved", "dedupKey", pagerDutyDedupKey(agentID))
+}
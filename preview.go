@@ -0,0 +1,242 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+// maxPreviewAgentNameLength keeps preview names usable as DNS labels
+const maxPreviewAgentNameLength = 63
+
+var previewNameInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// previewAgentName derives the name of the preview agent for the current pull
+// request, <base>-pr-<number>, or <base>-<branch> outside of pull requests.
+func previewAgentName(base string) (string, error) {
+	if base == "" {
+		return "", errors.New("AGENT_NAME or agent.name in livekit.toml must be set")
+	}
+
+	var suffix string
+	if number := pullRequestNumber(); number > 0 {
+		suffix = fmt.Sprintf("pr-%d", number)
+	} else {
+		branch := os.Getenv("GITHUB_HEAD_REF")
+		if branch == "" {
+			branch = os.Getenv("GITHUB_REF_NAME")
+		}
+		if branch == "" {
+			return "", errors.New("not a pull request and GITHUB_REF_NAME is not set")
+		}
+		suffix = branch
+	}
+
+	name := previewNameInvalidChars.ReplaceAllString(strings.ToLower(base+"-"+suffix), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > maxPreviewAgentNameLength {
+		name = strings.TrimRight(name[:maxPreviewAgentNameLength], "-")
+	}
+	return name, nil
+}
+
+// previewAgentBase is the name preview agent names are derived from: the
+// AGENT_NAME input, then agent.name in livekit.toml, then the repository name.
+func previewAgentBase(lkConfig *LiveKitTOML, agentName string) string {
+	if agentName != "" {
+		return agentName
+	}
+	if lkConfig != nil && lkConfig.HasAgent() && lkConfig.Agent.Name != "" {
+		return lkConfig.Agent.Name
+	}
+	return path.Base(os.Getenv("GITHUB_REPOSITORY"))
+}
+
+// deployPreviewAgent deploys the working directory to the preview agent for
+// the current pull request, creating it on the first run. livekit.toml in the
+// working directory is rewritten to reference the preview agent, so later
+// steps and operations in the job act on it.
+func deployPreviewAgent(client *cloudagents.Client, subdomain string, secrets []*livekit.AgentSecret, workingDir string, region string, agentName string, secretsMode string) {
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+	if !exists {
+		lkConfig = NewLiveKitTOML(subdomain)
+	}
+	if !lkConfig.HasAgent() {
+		lkConfig.WithDefaultAgent()
+	}
+
+	name, err := previewAgentName(previewAgentBase(lkConfig, agentName))
+	if err != nil {
+		log.Errorw("Failed to derive preview agent name", err)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentName: name,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agents", err)
+		exit(1)
+	}
+	if len(res.Agents) > 1 {
+		log.Errorw("Multiple agents found with the preview agent name", nil, "name", name, "count", len(res.Agents))
+		exit(1)
+	}
+
+	if err := setOutput("preview_agent_name", name); err != nil {
+		log.Warnw("Failed to write output", err, "output", "preview_agent_name")
+	}
+
+	lkConfig.Agent.Name = name
+	if len(res.Agents) == 1 {
+		lkConfig.Agent.ID = res.Agents[0].AgentId
+		if err := lkConfig.SaveTOMLFile(workingDir, LiveKitTOMLFile); err != nil {
+			log.Errorw("Failed to save livekit.toml", err)
+			exit(1)
+		}
+		log.Infow("Deploying to existing preview agent", "agent", lkConfig.Agent.ID, "name", name)
+		deployAgent(client, secrets, workingDir, secretsMode)
+		return
+	}
+
+	regions := lkConfig.Agent.Regions
+	if region != "" {
+		regions = []string{region}
+	}
+	log.Infow("Creating preview agent", "name", name, "regions", regions)
+	startGroup(buildGroup)
+	excludes := sourceExcludes(workingDir)
+	checkUploadSize(workingDir, excludes)
+	streamBuildOutput()
+	resp, err := client.CreateAgent(
+		context.Background(),
+		newSourceFS(workingDir),
+		secrets,
+		regions,
+		excludes,
+	)
+	endBuildOutput()
+	if err != nil {
+		log.Errorw("Failed to create agent", err)
+		exit(1)
+	}
+	endGroup()
+	notify(notification{Event: eventProgress, Message: fmt.Sprintf("Preview agent %s uploaded and built", resp.AgentId), AgentID: resp.AgentId})
+
+	lkConfig.Agent.ID = resp.AgentId
+	if err := lkConfig.SaveTOMLFile(workingDir, LiveKitTOMLFile); err != nil {
+		log.Errorw("Failed to save livekit.toml", err)
+		exit(1)
+	}
+
+	updateRes, err := client.UpdateAgent(context.Background(), &livekit.UpdateAgentRequest{
+		AgentId:   resp.AgentId,
+		AgentName: name,
+	})
+	if err != nil {
+		log.Errorw("Failed to name preview agent", err)
+		exit(1)
+	}
+	if !updateRes.Success {
+		log.Errorw("Failed to name preview agent", errors.New(updateRes.Message))
+		exit(1)
+	}
+
+	log.Infow("Preview agent created", "agent", resp.AgentId, "name", name)
+}
+
+// cleanupPreviewAgent deletes the preview agent for the current pull request,
+// clearing its secrets first so no values outlive the agent. It succeeds when
+// there is no preview agent to delete.
+func cleanupPreviewAgent(client *cloudagents.Client, workingDir string, agentName string, dryRun bool) {
+	lkConfig, _, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err != nil {
+		log.Errorw("Failed to load livekit.toml", err)
+		exit(1)
+	}
+
+	name, err := previewAgentName(previewAgentBase(lkConfig, agentName))
+	if err != nil {
+		log.Errorw("Failed to derive preview agent name", err)
+		exit(1)
+	}
+
+	res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+		AgentName: name,
+	})
+	if err != nil {
+		log.Errorw("Failed to list agents", err)
+		exit(1)
+	}
+	if len(res.Agents) == 0 {
+		log.Infow("No preview agent to delete", "name", name)
+		return
+	}
+
+	for _, agent := range res.Agents {
+		// the name lookup may not be exact, never delete an agent outside the convention
+		if agent.AgentName != name {
+			continue
+		}
+		if dryRun {
+			log.Infow("Dry run, preview agent not deleted", "agent", agent.AgentId, "name", name)
+			continue
+		}
+
+		secretsRes, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+			AgentId: agent.AgentId,
+		})
+		if err != nil {
+			log.Errorw("Failed to list agent secrets", err)
+			exit(1)
+		}
+		if len(secretsRes.Secrets) > 0 {
+			resp, err := client.UpdateAgentSecrets(context.Background(), &livekit.UpdateAgentSecretsRequest{
+				AgentId:   agent.AgentId,
+				Overwrite: true,
+			})
+			if err != nil {
+				log.Errorw("Failed to delete agent secrets", err)
+				exit(1)
+			}
+			if !resp.Success {
+				log.Errorw("Failed to delete agent secrets", errors.New(resp.Message))
+				exit(1)
+			}
+			log.Infow("Preview agent secrets deleted", "agent", agent.AgentId, "count", len(secretsRes.Secrets))
+		}
+
+		if _, err := client.DeleteAgent(context.Background(), &livekit.DeleteAgentRequest{
+			AgentId: agent.AgentId,
+		}); err != nil {
+			log.Errorw("Failed to delete agent", err)
+			exit(1)
+		}
+		log.Infow("Preview agent deleted", "agent", agent.AgentId, "name", name)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// releaseVersion is the released version being deployed: the RELEASE_VERSION
+// input, then the tag of the release or tag that triggered the workflow. It is
+// empty for other events.
+func releaseVersion() string {
+	if version := strings.TrimSpace(os.Getenv("INPUT_RELEASE_VERSION")); version != "" {
+		return version
+	}
+	if event := loadGitHubEvent(); event.Release != nil && event.Release.TagName != "" {
+		return event.Release.TagName
+	}
+	if os.Getenv("GITHUB_REF_TYPE") == "tag" {
+		return os.Getenv("GITHUB_REF_NAME")
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// operationResult is the result output, the whole outcome of the operation
+// as one JSON value for steps that read it with fromJSON().
+type operationResult struct {
+	Operation       string    `json:"operation"`
+	Success         bool      `json:"success"`
+	ExitCode        int       `json:"exit_code"`
+	AgentID         string    `json:"agent_id,omitempty"`
+	DeploymentID    string    `json:"deployment_id,omitempty"`
+	Version         string    `json:"version,omitempty"`
+	Status          string    `json:"status,omitempty"`
+	Regions         []string  `json:"regions"`
+	Release         string    `json:"release,omitempty"`
+	Environment     string    `json:"environment,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	CompletedAt     time.Time `json:"completed_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Phases          []phase   `json:"phases"`
+	Warnings        []string  `json:"warnings"`
+}
+
+// result is filled in as the operation runs and written when it exits.
+var result = &operationResult{Regions: []string{}}
+
+// setReport records the agent the operation ran against.
+func (r *operationResult) setReport(report *operationReport) {
+	r.AgentID = report.AgentID
+	if report.Agent == nil {
+		return
+	}
+	r.DeploymentID = report.Agent.AgentId + "@" + report.Agent.Version
+	r.Version = report.Agent.Version
+	r.Status = agentOverallStatus(report.Agent)
+	for _, deployment := range report.Agent.AgentDeployments {
+		r.Regions = append(r.Regions, deployment.Region)
+	}
+}
+
+// writeResultOutput sets the result output. It runs after the exit hooks, so
+// the agent and every phase and warning are included.
+func writeResultOutput(code int) {
+	result.Success = code == 0
+	result.ExitCode = code
+	result.Release = releaseVersion()
+	result.Environment = environmentName()
+	result.CompletedAt = time.Now().UTC()
+	if !result.StartedAt.IsZero() {
+		result.DurationSeconds = result.CompletedAt.Sub(result.StartedAt).Round(time.Millisecond).Seconds()
+	}
+	result.Phases = append([]phase{}, phases...)
+
+	loggedMu.Lock()
+	result.Warnings = append([]string{}, warnings...)
+	loggedMu.Unlock()
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Warnw("Failed to encode result", err)
+		return
+	}
+	if err := setOutput("result", string(data)); err != nil {
+		log.Warnw("Failed to write output", err, "output", "result")
+	}
+}
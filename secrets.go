@@ -0,0 +1,551 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getsops/sops/v3/decrypt"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// The size limits are conservative estimates rather than documented LiveKit
+// Cloud limits, so exceeding them only warns and the server has the final say.
+const (
+	maxSecretNameLength = 128
+	maxSecretCount      = 100
+	maxSecretValueSize  = 64 * 1024
+	maxSecretsSize      = 512 * 1024
+)
+
+var (
+	secretNamePattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	secretNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// deriveSecretName turns the ID of a secret in an external store, such as
+// openai-key, into a valid agent secret name, OPENAI_KEY.
+func deriveSecretName(id string) string {
+	name := strings.ToUpper(secretNameInvalidChars.ReplaceAllString(id, "_"))
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// reservedSecretNames are set by the agent runtime and would break the
+// container if overridden.
+var reservedSecretNames = map[string]bool{
+	"PATH":            true,
+	"HOME":            true,
+	"HOSTNAME":        true,
+	"PWD":             true,
+	"USER":            true,
+	"SHELL":           true,
+	"LD_PRELOAD":      true,
+	"LD_LIBRARY_PATH": true,
+}
+
+// validateSecrets checks every secret name, returning one error per problem so
+// they can all be fixed at once before anything is uploaded.
+func validateSecrets(secrets []*livekit.AgentSecret) []error {
+	var errs []error
+	for _, secret := range secrets {
+		if err := validateSecretName(secret.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// checkSecretSizes reports the secrets that exceed the expected size limits.
+func checkSecretSizes(secrets []*livekit.AgentSecret) []error {
+	var warnings []error
+	if len(secrets) > maxSecretCount {
+		warnings = append(warnings, fmt.Errorf("%d secrets provided, more than the expected limit of %d", len(secrets), maxSecretCount))
+	}
+
+	total := 0
+	for _, secret := range secrets {
+		if len(secret.Value) > maxSecretValueSize {
+			warnings = append(warnings, fmt.Errorf("secret %s is %d bytes, more than the expected limit of %d bytes", secret.Name, len(secret.Value), maxSecretValueSize))
+		}
+		total += len(secret.Name) + len(secret.Value)
+	}
+	if total > maxSecretsSize {
+		warnings = append(warnings, fmt.Errorf("secrets total %d bytes, more than the expected limit of %d bytes", total, maxSecretsSize))
+	}
+	return warnings
+}
+
+func validateSecretName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("secret name cannot be empty")
+	case len(name) > maxSecretNameLength:
+		return fmt.Errorf("secret name %s is longer than %d characters", name, maxSecretNameLength)
+	case !secretNamePattern.MatchString(name):
+		return fmt.Errorf("secret name %q must contain only letters, digits and underscores, and cannot start with a digit", name)
+	case reservedSecretNames[strings.ToUpper(name)]:
+		return fmt.Errorf("secret name %s is reserved", name)
+	}
+	return nil
+}
+
+// defaultSecretPrecedence orders the secret sources from highest to lowest
+// precedence: values set directly in the workflow win over files, which win
+// over external secret managers. SECRET_LIST was always applied after the
+// SECRET_* env vars, so it keeps overriding them.
+var defaultSecretPrecedence = []string{
+	"SECRET_LIST", "env", "SECRETS_JSON", "SECRETS_FILE", "SOPS_FILE",
+	"AGE_FILE", "VAULT", "AWS", "GCP", "AZURE", "DOPPLER", "KUBERNETES",
+}
+
+type secretSource struct {
+	Name    string
+	Secrets []*livekit.AgentSecret
+}
+
+// mergeSecretSources combines the secrets of every source, keeping the value
+// from the highest precedence source when a name is set more than once. The
+// source each kept secret came from is returned by name.
+// precedence lists source names highest first, with unlisted sources following
+// in their default order. In "error" mode duplicates fail instead of warning.
+func mergeSecretSources(sources []secretSource, precedence []string, mode string) ([]*livekit.AgentSecret, map[string]string, error) {
+	switch mode {
+	case "", "warn", "error":
+	default:
+		return nil, nil, fmt.Errorf("invalid DUPLICATE_SECRETS %q, expected warn or error", mode)
+	}
+
+	rank := make(map[string]int)
+	for _, name := range append(slices.Clone(precedence), defaultSecretPrecedence...) {
+		if !slices.Contains(defaultSecretPrecedence, name) {
+			return nil, nil, fmt.Errorf("unknown secret source %q in SECRETS_PRECEDENCE, expected one of %s", name, strings.Join(defaultSecretPrecedence, ", "))
+		}
+		if _, ok := rank[name]; !ok {
+			rank[name] = len(rank)
+		}
+	}
+
+	var merged []*livekit.AgentSecret
+	from := make(map[string]string)
+	index := make(map[string]int)
+	for _, source := range sources {
+		for _, secret := range source.Secrets {
+			prev, ok := from[secret.Name]
+			if !ok {
+				from[secret.Name] = source.Name
+				index[secret.Name] = len(merged)
+				merged = append(merged, secret)
+				continue
+			}
+			if mode == "error" {
+				if prev == source.Name {
+					return nil, nil, fmt.Errorf("secret %s is set more than once by %s", secret.Name, source.Name)
+				}
+				return nil, nil, fmt.Errorf("secret %s is set by both %s and %s", secret.Name, prev, source.Name)
+			}
+			// within a source the last value wins, as it would in a dotenv file
+			if rank[source.Name] <= rank[prev] {
+				log.Warnw("Duplicate secret, overriding", nil, "secret", secret.Name, "source", source.Name, "overridden", prev)
+				from[secret.Name] = source.Name
+				merged[index[secret.Name]] = secret
+			} else {
+				log.Warnw("Duplicate secret, ignoring", nil, "secret", secret.Name, "source", source.Name, "kept", prev)
+			}
+		}
+	}
+	return merged, from, nil
+}
+
+// environmentSeparator splits an environment qualifier from the secret name,
+// as in STAGING__DATABASE_URL.
+const environmentSeparator = "__"
+
+// selectEnvironmentSecrets keeps the secrets qualified with environment, with
+// the qualifier removed, along with every unqualified secret. Secrets qualified
+// for another of the declared environments are dropped, and a qualified secret
+// overrides an unqualified one of the same name. Names with any other prefix,
+// such as Logging__LogLevel, are ordinary secrets.
+func selectEnvironmentSecrets(secrets []*livekit.AgentSecret, environment string, environments []string) []*livekit.AgentSecret {
+	qualifier := strings.ToUpper(environment) + environmentSeparator
+	declared := make(map[string]bool, len(environments))
+	for _, env := range environments {
+		declared[strings.ToUpper(env)] = true
+	}
+
+	qualified := make(map[string]*livekit.AgentSecret)
+	for _, secret := range secrets {
+		if name, ok := strings.CutPrefix(secret.Name, qualifier); ok && name != "" {
+			qualified[name] = &livekit.AgentSecret{Name: name, Value: secret.Value, Kind: secret.Kind}
+		}
+	}
+
+	var selected []*livekit.AgentSecret
+	for _, secret := range secrets {
+		if name, ok := strings.CutPrefix(secret.Name, qualifier); ok && name != "" {
+			log.Infow("Using secret for environment", "secret", name, "environment", environment)
+			selected = append(selected, qualified[name])
+			continue
+		}
+		if i := strings.Index(secret.Name, environmentSeparator); i > 0 && declared[strings.ToUpper(secret.Name[:i])] {
+			log.Debugw("Skipping secret for another environment", "secret", secret.Name[i+len(environmentSeparator):], "environment", secret.Name[:i])
+			continue
+		}
+		if _, ok := qualified[secret.Name]; ok {
+			log.Debugw("Secret overridden for environment", "secret", secret.Name, "environment", environment)
+			continue
+		}
+		selected = append(selected, secret)
+	}
+	return selected
+}
+
+// maskSecrets asks the GitHub Actions runner to redact every secret value
+// from the job logs. Each line of a multi-line value is masked on its own,
+// since the runner matches masks line by line.
+func maskSecrets(secrets []*livekit.AgentSecret) {
+	for _, secret := range secrets {
+		// binary file contents cannot appear in the text logs
+		if !utf8.Valid(secret.Value) {
+			continue
+		}
+		maskValue(string(secret.Value))
+	}
+}
+
+func maskValue(value string) {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fmt.Printf("::add-mask::%s\n", escapeWorkflowCommand(line))
+	}
+}
+
+// escapeWorkflowCommand encodes the characters that are not allowed in a
+// workflow command's data.
+func escapeWorkflowCommand(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// missingSecrets returns the required secret names that were not provided.
+func missingSecrets(required []string, secrets []*livekit.AgentSecret) []string {
+	provided := make(map[string]bool, len(secrets))
+	for _, secret := range secrets {
+		provided[secret.Name] = true
+	}
+	var missing []string
+	for _, name := range required {
+		if !provided[name] && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// fileSecretPrefix marks the env vars holding the path of a file secret.
+const fileSecretPrefix = "SECRETFILE_"
+
+// loadEnvSecrets loads every env var with one of the given prefixes as a
+// secret, named without the prefix. SECRETFILE_NAME vars hold the path of a
+// file whose contents are loaded as the NAME file secret.
+func loadEnvSecrets(prefixes []string) ([]*livekit.AgentSecret, error) {
+	secrets := make([]*livekit.AgentSecret, 0)
+	for _, env := range os.Environ() {
+		// ignore the SECRET_LIST env var
+		if strings.HasPrefix(env, "SECRET_LIST=") {
+			continue
+		}
+
+		// SECRETFILE_NAME=path sends the contents of a file, as-is. It has its
+		// own prefix so SECRET_FILE_* values keep being sent as they are.
+		if rest, ok := strings.CutPrefix(env, fileSecretPrefix); ok {
+			fileName, path, _ := strings.Cut(rest, "=")
+			if fileName != "" {
+				secret, err := loadFileSecret(fileName, strings.TrimSpace(path))
+				if err != nil {
+					return nil, err
+				}
+				secrets = append(secrets, secret)
+				continue
+			}
+		}
+
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(env, prefix) {
+				continue
+			}
+
+			secretParts := strings.SplitN(strings.TrimPrefix(env, prefix), "=", 2)
+			secretName := secretParts[0]
+			secretValue := strings.TrimSpace(secretParts[1])
+
+			log.Infow("Loading secret", "secret", secretName, "prefix", prefix)
+			secrets = append(secrets, &livekit.AgentSecret{
+				Name:  secretName,
+				Value: []byte(secretValue),
+			})
+			break
+		}
+	}
+	return secrets, nil
+}
+
+// loadFileSecret reads a file secret's contents without any trimming or
+// decoding, so newlines and binary content are preserved. The secret is sent
+// with the file kind, to be mounted as a file in the agent rather than set as
+// an env var.
+func loadFileSecret(name string, path string) (*livekit.AgentSecret, error) {
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file for secret %s: %w", name, err)
+	}
+	log.Infow("Loading secret from file", "secret", name, "path", path)
+	return &livekit.AgentSecret{
+		Name:  name,
+		Value: value,
+		Kind:  livekit.AgentSecretKind_AGENT_SECRET_KIND_FILE,
+	}, nil
+}
+
+// parseSecretList parses a comma separated list of SECRET_NAME=SECRET_VALUE.
+// When the list contains newlines it is parsed as one SECRET_NAME=SECRET_VALUE
+// per line instead, so values may contain commas. Blank lines are ignored.
+func parseSecretList(list string) ([]*livekit.AgentSecret, error) {
+	separator := ","
+	if strings.Contains(list, "\n") {
+		separator = "\n"
+	}
+
+	var secrets []*livekit.AgentSecret
+	for i, secret := range strings.Split(list, separator) {
+		if separator == "\n" && strings.TrimSpace(secret) == "" {
+			continue
+		}
+		secretParts := strings.SplitN(secret, "=", 2)
+		if len(secretParts) != 2 {
+			return nil, fmt.Errorf("invalid secret format in SECRET_LIST entry %d, expected NAME=VALUE", i+1)
+		}
+
+		secretName := strings.TrimSpace(secretParts[0])
+		secretValue := strings.TrimSpace(secretParts[1])
+		log.Infow("Loading secret from SECRET_LIST", "secret", secretName)
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  secretName,
+			Value: []byte(secretValue),
+		})
+	}
+	return secrets, nil
+}
+
+// parseSecretsJSON parses either an object of name to value, or an array of
+// {"name": ..., "value": ...} objects. Values are used verbatim.
+func parseSecretsJSON(data string) ([]*livekit.AgentSecret, error) {
+	data = strings.TrimSpace(data)
+
+	var secrets []*livekit.AgentSecret
+	if strings.HasPrefix(data, "[") {
+		var entries []struct {
+			Name  string  `json:"name"`
+			Value *string `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			return nil, fmt.Errorf("invalid SECRETS_JSON: %w", err)
+		}
+		for i, entry := range entries {
+			if entry.Name == "" || entry.Value == nil {
+				return nil, fmt.Errorf("invalid SECRETS_JSON entry %d, expected name and value", i+1)
+			}
+			secrets = append(secrets, &livekit.AgentSecret{
+				Name:  entry.Name,
+				Value: []byte(*entry.Value),
+			})
+		}
+	} else {
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			return nil, fmt.Errorf("invalid SECRETS_JSON: %w", err)
+		}
+		for name, raw := range entries {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, fmt.Errorf("invalid SECRETS_JSON value for %s, expected a string", name)
+			}
+			secrets = append(secrets, &livekit.AgentSecret{
+				Name:  name,
+				Value: []byte(value),
+			})
+		}
+		// map iteration is random, keep the order stable
+		slices.SortFunc(secrets, func(a, b *livekit.AgentSecret) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+
+	for _, secret := range secrets {
+		log.Infow("Loading secret from SECRETS_JSON", "secret", secret.Name)
+	}
+	return secrets, nil
+}
+
+// loadSecretsFile loads secrets from a dotenv file, following the usual
+// quoting rules (single, double and multi-line quoted values, escapes, export).
+func loadSecretsFile(path string) ([]*livekit.AgentSecret, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := godotenv.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	for name, value := range entries {
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  name,
+			Value: []byte(value),
+		})
+	}
+	slices.SortFunc(secrets, func(a, b *livekit.AgentSecret) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, secret := range secrets {
+		log.Infow("Loading secret from SECRETS_FILE", "secret", secret.Name, "path", path)
+	}
+	return secrets, nil
+}
+
+// parseSopsEntries parses the top-level keys of decrypted SOPS cleartext.
+// Scalars are kept as written, so numbers such as 00123 or 1.10 are not
+// rewritten, while nested values are forwarded as JSON.
+func parseSopsEntries(cleartext []byte, format string) (map[string]string, error) {
+	switch format {
+	case "dotenv":
+		return godotenv.Unmarshal(string(cleartext))
+	case "json":
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(cleartext, &values); err != nil {
+			return nil, err
+		}
+		entries := make(map[string]string, len(values))
+		for name, raw := range values {
+			var value string
+			if err := json.Unmarshal(raw, &value); err == nil {
+				entries[name] = value
+				continue
+			}
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, raw); err != nil {
+				return nil, fmt.Errorf("failed to encode %s: %w", name, err)
+			}
+			entries[name] = compacted.String()
+		}
+		return entries, nil
+	default:
+		var doc yaml.Node
+		if err := yaml.Unmarshal(cleartext, &doc); err != nil {
+			return nil, err
+		}
+		entries := make(map[string]string)
+		if len(doc.Content) == 0 {
+			return entries, nil
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("expected a mapping of secret names to values")
+		}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			name, value := root.Content[i].Value, root.Content[i+1]
+			if value.Kind == yaml.AliasNode {
+				value = value.Alias
+			}
+			if value.Kind == yaml.ScalarNode {
+				entries[name] = value.Value
+				continue
+			}
+			var nested any
+			if err := value.Decode(&nested); err != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+			}
+			data, err := json.Marshal(nested)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s: %w", name, err)
+			}
+			entries[name] = string(data)
+		}
+		return entries, nil
+	}
+}
+
+// loadSopsFile decrypts a SOPS encrypted YAML, JSON or dotenv file in memory
+// and loads its top-level keys as secrets. Keys are resolved by SOPS itself,
+// e.g. SOPS_AGE_KEY for age or the ambient AWS, GCP and Azure credentials for KMS.
+func loadSopsFile(path string) ([]*livekit.AgentSecret, error) {
+	format := sopsFormat(path)
+	cleartext, err := decrypt.File(path, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	entries, err := parseSopsEntries(cleartext, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	for name, value := range entries {
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  name,
+			Value: []byte(value),
+		})
+	}
+	slices.SortFunc(secrets, func(a, b *livekit.AgentSecret) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, secret := range secrets {
+		log.Infow("Loading secret from SOPS_FILE", "secret", secret.Name, "path", path)
+	}
+	return secrets, nil
+}
+
+func sopsFormat(path string) string {
+	switch ext := filepath.Ext(path); {
+	case ext == ".yaml" || ext == ".yml":
+		return "yaml"
+	case ext == ".json":
+		return "json"
+	default:
+		return "dotenv"
+	}
+}
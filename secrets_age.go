@@ -0,0 +1,90 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/joho/godotenv"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// loadAgeBundle decrypts an age encrypted secrets bundle in memory and loads
+// its entries. The bundle is a dotenv file, or a JSON object when its name
+// ends in .json.age, and may be binary or ASCII armored. identities holds one
+// or more age identities, one per line.
+func loadAgeBundle(path string, identities string) ([]*livekit.AgentSecret, error) {
+	ids, err := age.ParseIdentities(strings.NewReader(identities))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AGE_KEY: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	in := bufio.NewReader(f)
+	var src io.Reader = in
+	if start, _ := in.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(in)
+	}
+	r, err := age.Decrypt(src, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	cleartext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	var entries map[string]string
+	if filepath.Ext(strings.TrimSuffix(path, ".age")) == ".json" {
+		err = json.Unmarshal(cleartext, &entries)
+	} else {
+		entries, err = godotenv.Parse(bytes.NewReader(cleartext))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	for name, value := range entries {
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  name,
+			Value: []byte(value),
+		})
+	}
+	slices.SortFunc(secrets, func(a, b *livekit.AgentSecret) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, secret := range secrets {
+		log.Infow("Loading secret from AGE_FILE", "secret", secret.Name, "path", path)
+	}
+	return secrets, nil
+}
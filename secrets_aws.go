@@ -0,0 +1,142 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+type awsSecretsConfig struct {
+	// SecretIDs and Parameters are entries of the form ID or ID=AGENT_SECRET_NAME
+	SecretIDs  []string
+	Parameters []string
+	// ExpandJSON loads each key of a JSON object value as its own secret
+	ExpandJSON bool
+}
+
+// loadAWSSecrets reads secrets from AWS Secrets Manager and SSM Parameter
+// Store, using the default credential chain (e.g. from aws-actions/configure-aws-credentials).
+func loadAWSSecrets(ctx context.Context, conf awsSecretsConfig) ([]*livekit.AgentSecret, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS credentials: %w", err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	if len(conf.SecretIDs) > 0 {
+		sm := secretsmanager.NewFromConfig(cfg)
+		for _, entry := range conf.SecretIDs {
+			id, name := splitSecretMapping(entry)
+			res, err := sm.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+				SecretId: aws.String(id),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read AWS secret %s: %w", id, err)
+			}
+
+			value := []byte(aws.ToString(res.SecretString))
+			if res.SecretString == nil {
+				value = res.SecretBinary
+			}
+			loaded, err := awsSecretValue(name, value, conf.ExpandJSON)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read AWS secret %s: %w", id, err)
+			}
+			for _, secret := range loaded {
+				log.Infow("Loading secret from AWS Secrets Manager", "secret", secret.Name, "id", id)
+			}
+			secrets = append(secrets, loaded...)
+		}
+	}
+
+	if len(conf.Parameters) > 0 {
+		client := ssm.NewFromConfig(cfg)
+		for _, entry := range conf.Parameters {
+			id, name := splitSecretMapping(entry)
+			res, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+				Name:           aws.String(id),
+				WithDecryption: aws.Bool(true),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read SSM parameter %s: %w", id, err)
+			}
+
+			loaded, err := awsSecretValue(name, []byte(aws.ToString(res.Parameter.Value)), conf.ExpandJSON)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read SSM parameter %s: %w", id, err)
+			}
+			for _, secret := range loaded {
+				log.Infow("Loading secret from SSM Parameter Store", "secret", secret.Name, "parameter", id)
+			}
+			secrets = append(secrets, loaded...)
+		}
+	}
+	return secrets, nil
+}
+
+func awsSecretValue(name string, value []byte, expandJSON bool) ([]*livekit.AgentSecret, error) {
+	if !expandJSON {
+		return []*livekit.AgentSecret{{Name: name, Value: value}}, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(value, &fields); err != nil {
+		// not a JSON object, load it as a single secret
+		return []*livekit.AgentSecret{{Name: name, Value: value}}, nil
+	}
+
+	var secrets []*livekit.AgentSecret
+	for key, v := range fields {
+		s, ok := v.(string)
+		if !ok {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			s = string(data)
+		}
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  key,
+			Value: []byte(s),
+		})
+	}
+	slices.SortFunc(secrets, func(a, b *livekit.AgentSecret) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return secrets, nil
+}
+
+// splitSecretMapping splits an ID=NAME entry. Without an explicit name, the
+// last path segment of the ID is used as a secret name, e.g. prod/openai-key
+// becomes OPENAI_KEY.
+func splitSecretMapping(entry string) (id string, name string) {
+	if i := strings.LastIndex(entry, "="); i > 0 {
+		return strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+	}
+	id = strings.TrimSpace(entry)
+	name = deriveSecretName(id[strings.LastIndexAny(id, "/:")+1:])
+	return id, name
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// loadAzureSecrets reads secrets from Azure Key Vault. Entries are a secret
+// name, or NAME=AGENT_SECRET_NAME since Key Vault names cannot contain underscores.
+func loadAzureSecrets(ctx context.Context, vaultURL string, entries []string) ([]*livekit.AgentSecret, error) {
+	cred, err := azureCredential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Azure credentials: %w", err)
+	}
+	client, err := azsecrets.NewClient(vaultURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Key Vault client: %w", err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	for _, entry := range entries {
+		id, name := splitSecretMapping(entry)
+		res, err := client.GetSecret(ctx, id, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Azure secret %s: %w", id, err)
+		}
+		if res.Value == nil {
+			return nil, fmt.Errorf("Azure secret %s has no value", id)
+		}
+		log.Infow("Loading secret from Azure Key Vault", "secret", name, "id", id)
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  name,
+			Value: []byte(*res.Value),
+		})
+	}
+	return secrets, nil
+}
+
+// azureCredential uses a client secret when one is configured, and otherwise
+// federates the workflow's OIDC token with the app registration in AZURE_CLIENT_ID.
+func azureCredential() (azcore.TokenCredential, error) {
+	tenantID, clientID := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+	if os.Getenv("AZURE_CLIENT_SECRET") != "" {
+		return azidentity.NewClientSecretCredential(tenantID, clientID, os.Getenv("AZURE_CLIENT_SECRET"), nil)
+	}
+	if tenantID == "" || clientID == "" {
+		return nil, fmt.Errorf("AZURE_TENANT_ID and AZURE_CLIENT_ID must be set")
+	}
+	return azidentity.NewClientAssertionCredential(tenantID, clientID, func(ctx context.Context) (string, error) {
+		return requestGitHubIDToken(ctx, "api://AzureADTokenExchange")
+	}, nil)
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const dopplerDownloadURL = "https://api.doppler.com/v3/configs/config/secrets/download"
+
+// dopplerMetadataSecrets are added to every config by Doppler and describe it
+// rather than hold a secret.
+var dopplerMetadataSecrets = map[string]bool{
+	"DOPPLER_PROJECT":     true,
+	"DOPPLER_CONFIG":      true,
+	"DOPPLER_ENVIRONMENT": true,
+}
+
+type dopplerConfig struct {
+	Token   string
+	Project string
+	Config  string
+	Include []string
+	Exclude []string
+}
+
+// loadDopplerSecrets downloads the secrets of a Doppler config. Service tokens
+// are scoped to a single config, so Project and Config are only needed for
+// personal or service account tokens. Include and Exclude are name globs.
+func loadDopplerSecrets(ctx context.Context, cfg dopplerConfig) ([]*livekit.AgentSecret, error) {
+	query := url.Values{"format": {"json"}}
+	if cfg.Project != "" {
+		query.Set("project", cfg.Project)
+	}
+	if cfg.Config != "" {
+		query.Set("config", cfg.Config)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dopplerDownloadURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download Doppler secrets: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to download Doppler secrets: %s: %s", resp.Status, body)
+	}
+
+	var values map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to decode Doppler secrets: %w", err)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if dopplerMetadataSecrets[name] {
+			continue
+		}
+		include, err := matchSecretName(name, cfg.Include, cfg.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		if include {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	secrets := make([]*livekit.AgentSecret, 0, len(names))
+	for _, name := range names {
+		log.Infow("Loading secret from Doppler", "secret", name)
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  name,
+			Value: []byte(values[name]),
+		})
+	}
+	return secrets, nil
+}
+
+// matchSecretName reports whether name matches one of the include globs, or
+// any name when there are none, and none of the exclude globs.
+func matchSecretName(name string, include, exclude []string) (bool, error) {
+	matched := len(include) == 0
+	for _, pattern := range include {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid secret pattern %q: %w", pattern, err)
+		}
+		if ok {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false, nil
+	}
+	for _, pattern := range exclude {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid secret pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+
+// loadGCPSecrets reads secrets from Google Secret Manager using Application
+// Default Credentials, such as the Workload Identity Federation credentials
+// written by google-github-actions/auth. Mappings are of the form
+// projects/PROJECT/secrets/SECRET[/versions/VERSION]:AGENT_SECRET_NAME.
+func loadGCPSecrets(ctx context.Context, mappings []string) ([]*livekit.AgentSecret, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Google credentials: %w", err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	for _, mapping := range mappings {
+		resource, name, _ := strings.Cut(mapping, ":")
+		resource = strings.TrimSpace(resource)
+		parts := strings.Split(resource, "/")
+		if len(parts) != 4 && len(parts) != 6 || parts[0] != "projects" || parts[2] != "secrets" {
+			return nil, fmt.Errorf("invalid Google secret %s, expected projects/PROJECT/secrets/SECRET[/versions/VERSION]", resource)
+		}
+		if len(parts) == 4 {
+			resource += "/versions/latest"
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			name = deriveSecretName(parts[3])
+		}
+
+		value, err := accessGCPSecret(ctx, client, resource)
+		if err != nil {
+			return nil, err
+		}
+		log.Infow("Loading secret from Google Secret Manager", "secret", name, "resource", resource)
+		secrets = append(secrets, &livekit.AgentSecret{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return secrets, nil
+}
+
+func accessGCPSecret(ctx context.Context, client *http.Client, resource string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpSecretManagerURL+resource+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access Google secret %s: %w", resource, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to access Google secret %s: %s: %s", resource, resp.Status, body)
+	}
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode Google secret %s: %w", resource, err)
+	}
+	return base64.StdEncoding.DecodeString(body.Payload.Data)
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// loadKubernetesSecrets reads the keys of Kubernetes Secrets, given as NAME or
+// NAMESPACE/NAME. Credentials come from KUBECONFIG, ~/.kube/config, or the
+// in-cluster service account, the same way kubectl finds them.
+func loadKubernetesSecrets(ctx context.Context, kubeContext string, names []string) ([]*livekit.AgentSecret, error) {
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	)
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes credentials: %w", err)
+	}
+	defaultNamespace, _, err := loader.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes namespace: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	var secrets []*livekit.AgentSecret
+	for _, entry := range names {
+		namespace, name, ok := strings.Cut(entry, "/")
+		if !ok {
+			namespace, name = defaultNamespace, entry
+		}
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kubernetes secret %s/%s: %w", namespace, name, err)
+		}
+
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			log.Infow("Loading secret from Kubernetes", "secret", key, "namespace", namespace, "name", name)
+			secrets = append(secrets, &livekit.AgentSecret{
+				Name:  key,
+				Value: secret.Data[key],
+			})
+		}
+	}
+	return secrets, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	onepassword "github.com/1password/onepassword-sdk-go"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const onePasswordReferencePrefix = "op://"
+
+// resolveOnePasswordReferences replaces secret values that are 1Password
+// secret references (op://vault/item/field) with the values they point to.
+func resolveOnePasswordReferences(ctx context.Context, token string, secrets []*livekit.AgentSecret) error {
+	var refs []*livekit.AgentSecret
+	for _, secret := range secrets {
+		if strings.HasPrefix(string(secret.Value), onePasswordReferencePrefix) {
+			refs = append(refs, secret)
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	client, err := onepassword.NewClient(ctx,
+		onepassword.WithServiceAccountToken(token),
+		onepassword.WithIntegrationInfo("LiveKit Cloud Agents GitHub Action", onepassword.DefaultIntegrationVersion),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create 1Password client: %w", err)
+	}
+
+	for _, secret := range refs {
+		ref := strings.TrimSpace(string(secret.Value))
+		value, err := client.Secrets().Resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve 1Password reference for secret %s: %w", secret.Name, err)
+		}
+		log.Infow("Resolved secret from 1Password", "secret", secret.Name, "reference", ref)
+		secret.Value = []byte(value)
+	}
+	return nil
+}
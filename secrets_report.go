@@ -0,0 +1,120 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+// secretReportEntry describes one provided secret for the audit report. It
+// never carries the value.
+type secretReportEntry struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Kind   string `json:"kind"`
+	// Status is new, changed or unchanged, or existing when the secret is
+	// already on the agent but there is no recorded hash to compare it with.
+	Status string `json:"status"`
+}
+
+// buildSecretReport lists every provided secret with the source it was loaded
+// from and how it compares to the agent's secrets, if the agent exists.
+func buildSecretReport(client *cloudagents.Client, workingDir string, secrets []*livekit.AgentSecret, origins map[string]string, environment string, stateFile string) ([]secretReportEntry, error) {
+	deployed := make(map[string]*livekit.AgentSecret)
+	var state *secretState
+	lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile)
+	if err == nil && exists && lkConfig.HasAgent() && lkConfig.Agent.ID != "" {
+		res, err := client.ListAgentSecrets(context.Background(), &livekit.ListAgentSecretsRequest{
+			AgentId: lkConfig.Agent.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list agent secrets: %w", err)
+		}
+		for _, secret := range res.Secrets {
+			deployed[secret.Name] = secret
+		}
+		if state, err = loadSecretState(stateFile, lkConfig.Agent.ID); err != nil {
+			return nil, fmt.Errorf("failed to load secrets state file: %w", err)
+		}
+	}
+
+	entries := make([]secretReportEntry, 0, len(secrets))
+	for _, secret := range secrets {
+		source, ok := origins[secret.Name]
+		if !ok && environment != "" {
+			source = origins[strings.ToUpper(environment)+environmentSeparator+secret.Name]
+		}
+		if source == "" {
+			source = "unknown"
+		}
+
+		kind := "environment"
+		if secret.Kind == livekit.AgentSecretKind_AGENT_SECRET_KIND_FILE {
+			kind = "file"
+		}
+
+		status := "new"
+		if current, ok := deployed[secret.Name]; ok {
+			switch {
+			case state == nil || state.Secrets[secret.Name].HMAC == "":
+				status = "existing"
+			case state.changed(secret, current):
+				status = "changed"
+			default:
+				status = "unchanged"
+			}
+		}
+
+		entries = append(entries, secretReportEntry{
+			Name:   secret.Name,
+			Source: source,
+			Kind:   kind,
+			Status: status,
+		})
+	}
+	return entries, nil
+}
+
+// writeSecretReport adds the report to the job summary and sets it as the
+// secrets_report step output, as JSON.
+func writeSecretReport(entries []secretReportEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := setOutput("secrets_report", string(data)); err != nil {
+		return err
+	}
+
+	var summary strings.Builder
+	summary.WriteString("### Agent Secrets\n\n")
+	if len(entries) == 0 {
+		summary.WriteString("No secrets provided.\n\n")
+		return appendStepSummary(summary.String())
+	}
+	summary.WriteString("| Secret | Source | Kind | Status |\n")
+	summary.WriteString("| --- | --- | --- | --- |\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&summary, "| `%s` | %s | %s | %s |\n", entry.Name, entry.Source, entry.Kind, entry.Status)
+	}
+	summary.WriteString("\n")
+	return appendStepSummary(summary.String())
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const defaultSecretStateFile = "/tmp/shared/livekit-secrets-state.json"
+
+// secretState records what was last sent for each secret of an agent. The
+// API never returns secret values, so changes are detected by comparing an
+// HMAC of the value, keyed with the API secret, and the server's updated_at,
+// which also catches secrets changed outside of this action.
+type secretState struct {
+	AgentID string                      `json:"agent_id"`
+	Secrets map[string]secretStateEntry `json:"secrets"`
+}
+
+type secretStateEntry struct {
+	HMAC      string    `json:"hmac"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// loadSecretState reads the state file, returning an empty state when it does
+// not exist yet or belongs to another agent.
+func loadSecretState(path string, agentID string) (*secretState, error) {
+	state := &secretState{AgentID: agentID, Secrets: make(map[string]secretStateEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var saved secretState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	if saved.AgentID == agentID && saved.Secrets != nil {
+		state.Secrets = saved.Secrets
+	}
+	return state, nil
+}
+
+func (s *secretState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// changed reports whether secret differs from what was last sent, given the
+// currently deployed copy, if any.
+func (s *secretState) changed(secret *livekit.AgentSecret, deployed *livekit.AgentSecret) bool {
+	entry, ok := s.Secrets[secret.Name]
+	if !ok || deployed == nil || deployed.UpdatedAt == nil {
+		return true
+	}
+	return entry.HMAC != secretHMAC(secret) || !entry.UpdatedAt.Equal(deployed.UpdatedAt.AsTime())
+}
+
+func (s *secretState) record(secret *livekit.AgentSecret, deployed *livekit.AgentSecret) {
+	entry := secretStateEntry{HMAC: secretHMAC(secret)}
+	if deployed != nil && deployed.UpdatedAt != nil {
+		entry.UpdatedAt = deployed.UpdatedAt.AsTime()
+	}
+	s.Secrets[secret.Name] = entry
+}
+
+func secretHMAC(secret *livekit.AgentSecret) string {
+	mac := hmac.New(sha256.New, []byte(lkApiSecret))
+	mac.Write([]byte(secret.Name))
+	mac.Write([]byte{0})
+	mac.Write(secret.Value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
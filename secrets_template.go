@@ -0,0 +1,85 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+type secretTemplateData struct {
+	Secrets map[string]string
+	Git     gitMetadata
+}
+
+type gitMetadata struct {
+	SHA        string
+	ShortSHA   string
+	Ref        string
+	Branch     string
+	Repository string
+}
+
+func loadGitMetadata() gitMetadata {
+	sha := os.Getenv("GITHUB_SHA")
+	short := sha
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return gitMetadata{
+		SHA:        sha,
+		ShortSHA:   short,
+		Ref:        os.Getenv("GITHUB_REF"),
+		Branch:     os.Getenv("GITHUB_REF_NAME"),
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+	}
+}
+
+// renderSecretTemplates expands Go template placeholders in secret values,
+// such as {{ .Secrets.DB_PASSWORD }} or {{ .Git.ShortSHA }}. Templates see the
+// values as they were provided, so a secret cannot refer to another rendered one.
+func renderSecretTemplates(secrets []*livekit.AgentSecret, git gitMetadata) error {
+	data := secretTemplateData{
+		Secrets: make(map[string]string, len(secrets)),
+		Git:     git,
+	}
+	for _, secret := range secrets {
+		data.Secrets[secret.Name] = string(secret.Value)
+	}
+
+	for _, secret := range secrets {
+		value := string(secret.Value)
+		// file secrets are sent byte for byte
+		if secret.Kind == livekit.AgentSecretKind_AGENT_SECRET_KIND_FILE || !strings.Contains(value, "{{") {
+			continue
+		}
+		tmpl, err := template.New(secret.Name).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid template in secret %s: %w", secret.Name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to render secret %s: %w", secret.Name, err)
+		}
+		log.Infow("Rendered secret template", "secret", secret.Name)
+		secret.Value = buf.Bytes()
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// secretPairs renders secrets as NAME=VALUE, in order.
+func secretPairs(secrets []*livekit.AgentSecret) []string {
+	var pairs []string
+	for _, secret := range secrets {
+		pairs = append(pairs, secret.Name+"="+string(secret.Value))
+	}
+	return pairs
+}
+
+func newSecrets(pairs ...string) []*livekit.AgentSecret {
+	var secrets []*livekit.AgentSecret
+	for _, pair := range pairs {
+		name, value, _ := strings.Cut(pair, "=")
+		secrets = append(secrets, &livekit.AgentSecret{Name: name, Value: []byte(value)})
+	}
+	return secrets
+}
+
+func TestParseSecretList(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "comma separated", list: "A=1, B = 2", want: []string{"A=1", "B=2"}},
+		{name: "value with equals", list: "URL=postgres://u:p@h/db?sslmode=require", want: []string{"URL=postgres://u:p@h/db?sslmode=require"}},
+		{name: "newline separated keeps commas", list: "A=1,2,3\n\nB=x\n", want: []string{"A=1,2,3", "B=x"}},
+		{name: "empty value", list: "A=", want: []string{"A="}},
+		{name: "missing equals", list: "A=1,B", wantErr: true},
+		{name: "missing equals on a line", list: "A=1\nB\n", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			secrets, err := parseSecretList(tc.list)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", secretPairs(secrets))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := secretPairs(secrets); !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSecretsJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "object sorted by name", data: `{"B": "2", "A": "a,b=c\nd"}`, want: []string{"A=a,b=c\nd", "B=2"}},
+		{name: "array keeps order", data: ` [{"name": "B", "value": "2"}, {"name": "A", "value": ""}]`, want: []string{"B=2", "A="}},
+		{name: "non-string value", data: `{"A": 1}`, wantErr: true},
+		{name: "array entry without value", data: `[{"name": "A"}]`, wantErr: true},
+		{name: "array entry without name", data: `[{"value": "1"}]`, wantErr: true},
+		{name: "invalid JSON", data: `{"A": `, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			secrets, err := parseSecretsJSON(tc.data)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", secretPairs(secrets))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := secretPairs(secrets); !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeSecretSources(t *testing.T) {
+	sources := []secretSource{
+		{Name: "env", Secrets: newSecrets("A=env", "B=env")},
+		{Name: "SECRET_LIST", Secrets: newSecrets("A=list", "C=list")},
+		{Name: "VAULT", Secrets: newSecrets("B=vault", "C=vault", "D=vault", "D=vault2")},
+	}
+
+	for _, tc := range []struct {
+		name       string
+		precedence []string
+		mode       string
+		want       []string
+		origins    map[string]string
+		wantErr    bool
+	}{
+		{
+			name:    "default precedence",
+			want:    []string{"A=list", "B=env", "C=list", "D=vault2"},
+			origins: map[string]string{"A": "SECRET_LIST", "B": "env", "C": "SECRET_LIST", "D": "VAULT"},
+		},
+		{
+			name:       "precedence moves sources to the top",
+			precedence: []string{"VAULT"},
+			want:       []string{"A=list", "B=vault", "C=vault", "D=vault2"},
+			origins:    map[string]string{"A": "SECRET_LIST", "B": "VAULT", "C": "VAULT", "D": "VAULT"},
+		},
+		{name: "duplicates fail in error mode", mode: "error", wantErr: true},
+		{name: "unknown source", precedence: []string{"NOPE"}, wantErr: true},
+		{name: "invalid mode", mode: "ignore", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, origins, err := mergeSecretSources(sources, tc.precedence, tc.mode)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", secretPairs(merged))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := secretPairs(merged); !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if !maps.Equal(origins, tc.origins) {
+				t.Errorf("got origins %v, want %v", origins, tc.origins)
+			}
+		})
+	}
+}
+
+func TestSelectEnvironmentSecrets(t *testing.T) {
+	secrets := newSecrets(
+		"DATABASE_URL=shared",
+		"STAGING__DATABASE_URL=staging",
+		"PROD__DATABASE_URL=prod",
+		"staging__API_KEY=staging-key",
+		"Logging__LogLevel=debug",
+		"OTEL__EXPORTER=otlp",
+	)
+
+	for _, tc := range []struct {
+		name         string
+		environment  string
+		environments []string
+		want         []string
+	}{
+		{
+			name:         "declared environments",
+			environment:  "staging",
+			environments: []string{"staging", "prod"},
+			want:         []string{"DATABASE_URL=staging", "Logging__LogLevel=debug", "OTEL__EXPORTER=otlp"},
+		},
+		{
+			name:         "lowercase qualifier",
+			environment:  "STAGING",
+			environments: []string{"STAGING", "PROD"},
+			want:         []string{"DATABASE_URL=staging", "Logging__LogLevel=debug", "OTEL__EXPORTER=otlp"},
+		},
+		{
+			name:        "undeclared prefixes are kept",
+			environment: "prod",
+			want:        []string{"STAGING__DATABASE_URL=staging", "DATABASE_URL=prod", "staging__API_KEY=staging-key", "Logging__LogLevel=debug", "OTEL__EXPORTER=otlp"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := secretPairs(selectEnvironmentSecrets(secrets, tc.environment, tc.environments))
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSopsEntries(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		format    string
+		cleartext string
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "YAML scalars as written",
+			format:    "yaml",
+			cleartext: "PIN: 00123\nOCTAL: 012\nVERSION: 1.10\nACCOUNT: 12345678901234567890123\nPORT: 8080\nDEBUG: true\nNAME: 'agent'\n",
+			want: map[string]string{
+				"PIN": "00123", "OCTAL": "012", "VERSION": "1.10", "ACCOUNT": "12345678901234567890123",
+				"PORT": "8080", "DEBUG": "true", "NAME": "agent",
+			},
+		},
+		{
+			name:      "YAML nested values as JSON",
+			format:    "yaml",
+			cleartext: "LIST: [1, a]\nMAP:\n  key: value\n",
+			want:      map[string]string{"LIST": `[1,"a"]`, "MAP": `{"key":"value"}`},
+		},
+		{
+			name:      "YAML aliases",
+			format:    "yaml",
+			cleartext: "A: &port 8080\nB: *port\n",
+			want:      map[string]string{"A": "8080", "B": "8080"},
+		},
+		{name: "YAML that is not a mapping", format: "yaml", cleartext: "- a\n- b\n", wantErr: true},
+		{
+			name:      "JSON numbers as written",
+			format:    "json",
+			cleartext: `{"ID": 123456789, "BIG": 1000000, "RATE": 1.10, "ON": true, "S": "x", "NESTED": {"a": [1, 2]}}`,
+			want: map[string]string{
+				"ID": "123456789", "BIG": "1000000", "RATE": "1.10", "ON": "true", "S": "x", "NESTED": `{"a":[1,2]}`,
+			},
+		},
+		{
+			name:      "dotenv",
+			format:    "dotenv",
+			cleartext: "A=1\nB=\"two words\"\n",
+			want:      map[string]string{"A": "1", "B": "two words"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSopsEntries([]byte(tc.cleartext), tc.format)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchSecretName(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		include, exclude []string
+		want             bool
+		wantErr          bool
+	}{
+		{name: "OPENAI_API_KEY", want: true},
+		{name: "OPENAI_API_KEY", include: []string{"*_API_KEY"}, want: true},
+		{name: "DATABASE_URL", include: []string{"*_API_KEY"}, want: false},
+		{name: "CI_API_KEY", include: []string{"*_API_KEY"}, exclude: []string{"CI_*"}, want: false},
+		{name: "CI_TOKEN", exclude: []string{"CI_*"}, want: false},
+		{name: "A", include: []string{"["}, wantErr: true},
+	} {
+		got, err := matchSecretName(tc.name, tc.include, tc.exclude)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("matchSecretName(%q, %q, %q) = %v, want an error", tc.name, tc.include, tc.exclude, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("matchSecretName(%q, %q, %q) = %v, %v, want %v", tc.name, tc.include, tc.exclude, got, err, tc.want)
+		}
+	}
+}
+
+func TestDeriveSecretName(t *testing.T) {
+	for _, tc := range []struct {
+		entry string
+		id    string
+		name  string
+	}{
+		{entry: "prod/openai-key", id: "prod/openai-key", name: "OPENAI_KEY"},
+		{entry: "arn:aws:secretsmanager:us-east-1:123456789012:secret:db.password", id: "arn:aws:secretsmanager:us-east-1:123456789012:secret:db.password", name: "DB_PASSWORD"},
+		{entry: "/prod/1st-token", id: "/prod/1st-token", name: "_1ST_TOKEN"},
+		{entry: "prod/openai-key=OPENAI_API_KEY", id: "prod/openai-key", name: "OPENAI_API_KEY"},
+	} {
+		id, name := splitSecretMapping(tc.entry)
+		if id != tc.id || name != tc.name {
+			t.Errorf("splitSecretMapping(%q) = %q, %q, want %q, %q", tc.entry, id, name, tc.id, tc.name)
+		}
+		if err := validateSecretName(name); err != nil {
+			t.Errorf("splitSecretMapping(%q) derived an invalid name: %v", tc.entry, err)
+		}
+	}
+}
+
+func TestValidateSecrets(t *testing.T) {
+	if errs := validateSecrets(newSecrets("A=1", "_B=2", "c3=3")); len(errs) > 0 {
+		t.Errorf("valid names reported %v", errs)
+	}
+	if errs := validateSecrets(newSecrets("=1", "1A=2", "A-B=3", "PATH=4")); len(errs) != 4 {
+		t.Errorf("got %d errors for 4 invalid names: %v", len(errs), errs)
+	}
+
+	large := &livekit.AgentSecret{Name: "LARGE", Value: make([]byte, maxSecretValueSize+1)}
+	if errs := validateSecrets([]*livekit.AgentSecret{large}); len(errs) > 0 {
+		t.Errorf("size limits failed validation: %v", errs)
+	}
+	if warnings := checkSecretSizes([]*livekit.AgentSecret{large}); len(warnings) != 1 {
+		t.Errorf("got %d warnings for a large value, want 1: %v", len(warnings), warnings)
+	}
+}
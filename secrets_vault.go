@@ -0,0 +1,132 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+type vaultConfig struct {
+	Address   string
+	Namespace string
+	Token     string
+	// Role and AuthPath are used to log in with the workflow's OIDC token
+	// when no token is set
+	Role     string
+	AuthPath string
+	Paths    []string
+}
+
+// loadVaultSecrets reads every key stored at the configured paths and loads
+// it as a secret of the same name. Both KV v1 and KV v2 (data/) paths are supported.
+func loadVaultSecrets(ctx context.Context, conf vaultConfig) ([]*livekit.AgentSecret, error) {
+	vc := vault.DefaultConfig()
+	vc.Address = conf.Address
+	client, err := vault.NewClient(vc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault client: %w", err)
+	}
+	if conf.Namespace != "" {
+		client.SetNamespace(conf.Namespace)
+	}
+
+	switch {
+	case conf.Token != "":
+		client.SetToken(conf.Token)
+	case conf.Role != "":
+		if err := vaultJWTLogin(ctx, client, conf); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("VAULT_TOKEN or VAULT_ROLE must be set")
+	}
+
+	var secrets []*livekit.AgentSecret
+	for _, path := range conf.Paths {
+		res, err := client.Logical().ReadWithContext(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Vault path %s: %w", path, err)
+		}
+		if res == nil || res.Data == nil {
+			return nil, fmt.Errorf("no secret found at Vault path %s", path)
+		}
+
+		data := res.Data
+		// KV v2 nests the values under data, next to the version metadata
+		if nested, ok := data["data"].(map[string]any); ok {
+			if _, ok := data["metadata"]; ok {
+				data = nested
+			}
+		}
+
+		var pathSecrets []*livekit.AgentSecret
+		for name, value := range data {
+			v, ok := value.(string)
+			if !ok {
+				// nested values and other scalars are forwarded as JSON
+				data, err := json.Marshal(value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode %s in Vault secret %s: %w", name, path, err)
+				}
+				v = string(data)
+			}
+			pathSecrets = append(pathSecrets, &livekit.AgentSecret{
+				Name:  name,
+				Value: []byte(v),
+			})
+		}
+		slices.SortFunc(pathSecrets, func(a, b *livekit.AgentSecret) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		for _, secret := range pathSecrets {
+			log.Infow("Loading secret from Vault", "secret", secret.Name, "path", path)
+		}
+		secrets = append(secrets, pathSecrets...)
+	}
+	return secrets, nil
+}
+
+func vaultJWTLogin(ctx context.Context, client *vault.Client, conf vaultConfig) error {
+	jwt, err := requestGitHubIDToken(ctx, conf.Address)
+	if err != nil {
+		return err
+	}
+
+	authPath := conf.AuthPath
+	if authPath == "" {
+		authPath = "jwt"
+	}
+	res, err := client.Logical().WriteWithContext(ctx, fmt.Sprintf("auth/%s/login", strings.Trim(authPath, "/")), map[string]any{
+		"role": conf.Role,
+		"jwt":  jwt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+	if res == nil || res.Auth == nil {
+		return errors.New("failed to log in to Vault: no token returned")
+	}
+	client.SetToken(res.Auth.ClientToken)
+	return nil
+}
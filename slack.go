@@ -0,0 +1,174 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+var slackMentionPattern = regexp.MustCompile(`^@?([UWS][A-Z0-9]+|!?(here|channel|everyone))$`)
+
+var (
+	// slackThread is set by SLACK_THREAD, to thread notifications under the
+	// started message
+	slackThread bool
+	// slackThreadTS is the timestamp of the message notifications are threaded
+	// under, from SLACK_THREAD_TS or the started message.
+	slackThreadTS string
+)
+
+// slackNotifier posts notifications to SLACK_CHANNEL as Block Kit blocks with
+// the agent's regional status and the run that sent it. The text is also
+// sent as plain text, for notifications and clients that do not render
+// blocks. Progress updates are only posted in a thread, as plain text.
+type slackNotifier struct{}
+
+func (slackNotifier) name() string { return "slack" }
+
+func (slackNotifier) configured() bool {
+	return getInputOrEnv("SLACK_TOKEN") != "" && getInputOrEnv("SLACK_CHANNEL") != ""
+}
+
+func (slackNotifier) supports(event string) bool {
+	return event != eventProgress || slackThreadTS != ""
+}
+
+func (slackNotifier) send(n notification) error {
+	slackToken := getInputOrEnv("SLACK_TOKEN")
+	slackChannel := getInputOrEnv("SLACK_CHANNEL")
+
+	text := n.text()
+	if n.Event == eventFailed || n.Event == eventAlert {
+		if mentions := slackMentions(getListInput("SLACK_MENTIONS")); mentions != "" {
+			text = mentions + " " + text
+		}
+	}
+	options := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if n.Event != eventProgress {
+		options = append(options, slack.MsgOptionBlocks(slackBlocks(text, n.data())...))
+	}
+	if slackThreadTS != "" {
+		options = append(options, slack.MsgOptionTS(slackThreadTS))
+	}
+	api := slack.New(slackToken)
+	_, ts, err := api.PostMessage(slackChannel, options...)
+	if err != nil {
+		return err
+	}
+
+	// the thread of an earlier job is kept, and set as the output again so
+	// each job can pass it on
+	if n.Event == eventStarted && slackThread {
+		if slackThreadTS == "" {
+			slackThreadTS = ts
+		}
+		if err := setOutput("slack_thread_ts", slackThreadTS); err != nil {
+			log.Warnw("Failed to write output", err, "output", "slack_thread_ts")
+		}
+	}
+	return nil
+}
+
+// slackMentions formats user IDs (U…, W…), user group IDs (S…), and here,
+// channel or everyone as Slack mentions, so failures ping whoever is on
+// rotation while other messages stay quiet.
+func slackMentions(ids []string) string {
+	var mentions []string
+	for _, id := range ids {
+		id = strings.TrimPrefix(strings.TrimPrefix(id, "@"), "!")
+		switch {
+		case id == "here" || id == "channel" || id == "everyone":
+			mentions = append(mentions, "<!"+id+">")
+		case strings.HasPrefix(id, "S"):
+			mentions = append(mentions, "<!subteam^"+id+">")
+		default:
+			mentions = append(mentions, "<@"+id+">")
+		}
+	}
+	return strings.Join(mentions, " ")
+}
+
+func slackBlocks(text string, d notificationData) []slack.Block {
+	markdown := func(s string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, s, false, false)
+	}
+	blocks := []slack.Block{slack.NewSectionBlock(markdown(text), nil, nil)}
+
+	var fields []*slack.TextBlockObject
+	field := func(name, value string) {
+		if value != "" {
+			fields = append(fields, markdown(fmt.Sprintf("*%s*\n%s", name, value)))
+		}
+	}
+	if d.Agent.ID != "" {
+		field("Agent", fmt.Sprintf("`%s`", d.Agent.ID))
+	}
+	if d.Agent.Version != "" {
+		field("Version", fmt.Sprintf("`%s`", d.Agent.Version))
+	}
+	field("Operation", d.Operation)
+	field("Environment", d.Environment)
+	if d.Git.CommitURL != "" {
+		field("Commit", fmt.Sprintf("<%s|%s>", d.Git.CommitURL, d.Git.ShortCommit))
+	} else if d.Git.ShortCommit != "" {
+		field("Commit", fmt.Sprintf("`%s`", d.Git.ShortCommit))
+	}
+	field("Actor", d.Git.Actor)
+	if d.Duration > 0 {
+		field("Duration", d.Duration.String())
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+
+	if len(d.Agent.Regions) > 0 {
+		var regions []string
+		for _, region := range d.Agent.Regions {
+			regions = append(regions, fmt.Sprintf("• %s: %s (%d / %d replicas)", region.Region, region.Status, region.Replicas, region.MaxReplicas))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(markdown("*Regions*\n"+strings.Join(regions, "\n")), nil, nil))
+	}
+	slackLink := func(text, url string) string { return fmt.Sprintf("<%s|%s>", url, text) }
+	if summary := d.changeSummary(slackLink); summary != "" {
+		blocks = append(blocks, slack.NewSectionBlock(markdown("*Changes*\n"+summary), nil, nil))
+	}
+	if summary := d.phaseSummary(); summary != "" {
+		blocks = append(blocks, slack.NewSectionBlock(markdown("*Phases*\n"+summary), nil, nil))
+	}
+	if len(d.Log) > 0 {
+		// section text is limited to 3000 characters
+		blocks = append(blocks, slack.NewSectionBlock(markdown("*Log*\n```"+d.logExcerpt(2900)+"```"), nil, nil))
+	}
+
+	var buttons []slack.BlockElement
+	button := func(id, text, url string) {
+		if url != "" {
+			b := slack.NewButtonBlockElement(id, "", slack.NewTextBlockObject(slack.PlainTextType, text, false, false))
+			b.URL = url
+			buttons = append(buttons, b)
+		}
+	}
+	button("workflow_run", "View workflow run", d.Git.RunURL)
+	button("compare", "Compare commits", d.Links.Compare)
+	button("dashboard", "LiveKit Cloud dashboard", d.Links.Dashboard)
+	if len(buttons) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("", buttons...))
+	}
+	return blocks
+}
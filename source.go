@@ -0,0 +1,426 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// these mirror the exclusions applied by cloudagents when packaging the source
+var (
+	defaultExcludePatterns = []string{
+		"Dockerfile",
+		".dockerignore",
+		".gitignore",
+		".git",
+		"node_modules",
+		".env",
+		".env.*",
+	}
+
+	ignoreFilePatterns = []string{
+		".gitignore",
+		".dockerignore",
+	}
+)
+
+// livekitIgnoreFile lists files to leave out of the upload, in gitignore
+// syntax.
+const livekitIgnoreFile = ".livekitignore"
+
+// sourceExcludes returns the patterns excluded from the upload of workingDir
+// on top of the cloudagents defaults: everything INCLUDE does not list,
+// livekit.toml, what git ignores with EXCLUDE_GITIGNORED, the .dockerignore,
+// what .livekitignore lists and EXCLUDE, with later patterns taking precedence.
+func sourceExcludes(workingDir string) []string {
+	var excludes []string
+	if include := getListInput("INCLUDE"); len(include) > 0 {
+		excludes = append(excludes, includePatterns(include)...)
+	}
+	excludes = append(excludes, LiveKitTOMLFile, livekitIgnoreFile)
+	if getBoolInput("EXCLUDE_GITIGNORED") {
+		excludes = append(excludes, gitignoredPatterns(workingDir)...)
+	}
+	excludes = append(excludes, dockerignorePatterns(workingDir)...)
+	// listed last, so it can re-include what git ignores
+	if content, err := os.ReadFile(filepath.Join(workingDir, livekitIgnoreFile)); err == nil {
+		patterns := gitignorePatterns(string(content), "")
+		log.Debugw("Excluding files listed in "+livekitIgnoreFile, "patterns", len(patterns))
+		excludes = append(excludes, patterns...)
+	}
+	for _, p := range getListInput("EXCLUDE") {
+		excludes = append(excludes, cleanSourcePattern(p))
+	}
+	return excludes
+}
+
+// includePatterns excludes everything but the files matching include, and
+// the directories leading to them so they are packaged too.
+func includePatterns(include []string) []string {
+	patterns := []string{"**"}
+	dirs := make(map[string]bool)
+	for _, p := range include {
+		p = cleanSourcePattern(p)
+		segments := strings.Split(p, "/")
+		for i := 1; i < len(segments); i++ {
+			if strings.ContainsAny(segments[i-1], "*?[\\") {
+				break
+			}
+			dir := strings.Join(segments[:i], "/")
+			if !dirs[dir] {
+				dirs[dir] = true
+				patterns = append(patterns, "!"+dir)
+			}
+		}
+		patterns = append(patterns, "!"+p)
+	}
+	return patterns
+}
+
+// cleanSourcePattern makes a glob relative to the working directory, as the
+// upload is matched.
+func cleanSourcePattern(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(p, "./")), "/")
+}
+
+// dockerignorePatterns reads the .dockerignore the build would use, so the
+// upload matches the build context: Dockerfile.dockerignore when there is one,
+// as BuildKit prefers it, and .dockerignore otherwise. cloudagents also reads
+// .dockerignore, but splits it into raw lines, so comments, leading slashes and
+// ./ prefixes are not handled as Docker does.
+func dockerignorePatterns(workingDir string) []string {
+	for _, name := range []string{"Dockerfile.dockerignore", ".dockerignore"} {
+		f, err := os.Open(filepath.Join(workingDir, name))
+		if err != nil {
+			continue
+		}
+		patterns, err := ignorefile.ReadAll(f)
+		f.Close()
+		if err != nil {
+			log.Warnw("Failed to read "+name+", it is not applied to the upload", err)
+			return nil
+		}
+		log.Debugw("Excluding files listed in "+name, "patterns", len(patterns))
+		return patterns
+	}
+	return nil
+}
+
+// gitignoreRule is a line of a gitignore file.
+type gitignoreRule struct {
+	pattern string
+	negate  bool
+	// anchored patterns contain a slash, other than a trailing one, and are
+	// relative to the ignore file rather than matching at any depth
+	anchored bool
+}
+
+func parseGitignore(content string) []gitignoreRule {
+	var rules []gitignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		p := strings.TrimSpace(line)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		// \# and \! escape a leading # or !
+		p = strings.TrimPrefix(p, `\`)
+		p = strings.TrimSuffix(p, "/")
+		if p == "" {
+			continue
+		}
+		anchored := strings.Contains(p, "/")
+		rules = append(rules, gitignoreRule{pattern: strings.TrimPrefix(p, "/"), negate: negate, anchored: anchored})
+	}
+	return rules
+}
+
+func (r gitignoreRule) String() string {
+	if r.negate {
+		return "!" + r.pattern
+	}
+	return r.pattern
+}
+
+// gitignorePatterns converts gitignore patterns from the ignore file in dir,
+// relative to the working directory, to the dockerignore syntax the upload is
+// filtered with. Patterns for directories also match files of that name.
+func gitignorePatterns(content string, dir string) []string {
+	var patterns []string
+	for _, rule := range parseGitignore(content) {
+		if rule.anchored {
+			rule.pattern = path.Join(dir, rule.pattern)
+		} else {
+			rule.pattern = path.Join(dir, "**", rule.pattern)
+		}
+		patterns = append(patterns, rule.String())
+	}
+	return patterns
+}
+
+// gitignoredPatterns returns the patterns of every .gitignore that applies to
+// workingDir: those of its parent directories up to the repository root, and
+// its own and its subdirectories'. Parents are only read when the repository
+// root, which has .git, is found.
+func gitignoredPatterns(workingDir string) []string {
+	abs, err := filepath.Abs(workingDir)
+	if err != nil {
+		log.Warnw("Failed to resolve the working directory, files ignored by git are uploaded", err)
+		return nil
+	}
+
+	var parents []string
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err != nil {
+		for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+			parents = append([]string{dir}, parents...)
+			if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+				break
+			}
+			if dir == filepath.Dir(dir) {
+				parents = nil
+				break
+			}
+		}
+	}
+
+	var patterns []string
+	for _, parent := range parents {
+		content, err := os.ReadFile(filepath.Join(parent, ".gitignore"))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(parent, abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel) + "/"
+		for _, rule := range parseGitignore(string(content)) {
+			switch {
+			case !rule.anchored:
+				rule.pattern = "**/" + rule.pattern
+			case strings.HasPrefix(rule.pattern, "**/"):
+			case strings.HasPrefix(rule.pattern, rel):
+				rule.pattern = strings.TrimPrefix(rule.pattern, rel)
+			default:
+				// anchored outside of the working directory
+				continue
+			}
+			patterns = append(patterns, rule.String())
+		}
+	}
+
+	// a directory's .gitignore is walked before its subdirectories, whose
+	// patterns take precedence as git's do
+	err = fs.WalkDir(os.DirFS(abs), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == ".git" || d.Name() == "node_modules") {
+			return fs.SkipDir
+		}
+		if d.IsDir() || d.Name() != ".gitignore" {
+			return nil
+		}
+		content, err := os.ReadFile(filepath.Join(abs, p))
+		if err != nil {
+			return err
+		}
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		patterns = append(patterns, gitignorePatterns(string(content), dir)...)
+		return nil
+	})
+	if err != nil {
+		log.Warnw("Failed to read .gitignore files, files ignored by git may be uploaded", err)
+	}
+	log.Debugw("Excluding files ignored by git", "patterns", len(patterns))
+	return patterns
+}
+
+// newSourceMatcher builds the matcher deciding which files are uploaded.
+func newSourceMatcher(dir fs.FS, excludeFiles []string) (*patternmatcher.PatternMatcher, error) {
+	patterns := append([]string{}, excludeFiles...)
+	patterns = append(patterns, defaultExcludePatterns...)
+
+	for _, ignoreFile := range ignoreFilePatterns {
+		content, err := fs.ReadFile(dir, ignoreFile)
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, strings.Split(string(content), "\n")...)
+	}
+
+	for i, p := range patterns {
+		patterns[i] = strings.TrimSpace(p)
+	}
+
+	matcher, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pattern matcher: %w", err)
+	}
+	return matcher, nil
+}
+
+func includeSourceFile(matcher *patternmatcher.PatternMatcher, p string) bool {
+	// the Dockerfile is always uploaded, as it is required for the build
+	if strings.Contains(path.Base(p), "Dockerfile") {
+		return true
+	}
+
+	ignored, err := matcher.MatchesOrParentMatches(p)
+	return !ignored && err == nil
+}
+
+// sourceFiles lists the regular files that would be uploaded, in lexical order.
+func sourceFiles(dir fs.FS, excludeFiles []string) ([]string, error) {
+	matcher, err := newSourceMatcher(dir, excludeFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = fs.WalkDir(dir, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !includeSourceFile(matcher, p) || !d.Type().IsRegular() {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return files, nil
+}
+
+// sourceHash returns a content hash of the files that would be uploaded.
+// It only depends on file paths and contents, so identical sources always hash
+// the same regardless of timestamps or permissions.
+func sourceHash(dir fs.FS, excludeFiles []string) (string, error) {
+	files, err := sourceFiles(dir, excludeFiles)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, p := range files {
+		f, err := dir.Open(p)
+		if err != nil {
+			return "", fmt.Errorf("failed to open file %s: %w", p, err)
+		}
+		fmt.Fprintf(h, "%s\x00", p)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeSourceArchive writes the tar.gz cloudagents would upload for dir to w,
+// with the same entries in the same order.
+func writeSourceArchive(dir fs.FS, excludeFiles []string, w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	if err := writeSourceTar(dir, excludeFiles, gzipWriter); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// sourceArchiveHash returns the SHA-256 of the uncompressed archive of dir,
+// which changes with the paths, contents and permissions of the uploaded
+// files but not with the compressor.
+func sourceArchiveHash(dir fs.FS, excludeFiles []string) (string, error) {
+	h := sha256.New()
+	if err := writeSourceTar(dir, excludeFiles, h); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeSourceTar writes the uncompressed tar of the files uploaded from dir.
+func writeSourceTar(dir fs.FS, excludeFiles []string, w io.Writer) error {
+	matcher, err := newSourceMatcher(dir, excludeFiles)
+	if err != nil {
+		return err
+	}
+
+	tarWriter := tar.NewWriter(w)
+	err = fs.WalkDir(dir, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !includeSourceFile(matcher, p) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("failed to create tar header for %s: %w", p, err)
+		}
+		header.Name = p
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %w", p, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := dir.Open(p)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", p, err)
+		}
+		defer f.Close()
+		if _, err := io.Copy(tarWriter, f); err != nil {
+			return fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return tarWriter.Close()
+}
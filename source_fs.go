@@ -0,0 +1,109 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// sourceModTime is the modification time of every uploaded file. Timestamps
+// before 1980 cannot be stored in zip files, which breaks building Python
+// wheels from the source, so the Unix epoch is not used.
+var sourceModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// sourceFS is the working directory as it is uploaded. Every file reports the
+// same modification time, permissions of 0644, or 0755 for directories and
+// executables, and no owner, so the archive cloudagents packages from it only
+// depends on the paths and contents of the files. Entries are already walked
+// in lexical order and the gzip header carries no name or time, so identical
+// sources produce identical archives.
+type sourceFS struct {
+	fsys fs.FS
+}
+
+func newSourceFS(workingDir string) fs.FS {
+	return sourceFS{fsys: os.DirFS(workingDir)}
+}
+
+func (s sourceFS) Open(name string) (fs.File, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return sourceFile{File: f}, nil
+}
+
+func (s sourceFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return sourceFileInfo{FileInfo: info}, nil
+}
+
+func (s sourceFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	for i, e := range entries {
+		entries[i] = sourceDirEntry{DirEntry: e}
+	}
+	return entries, err
+}
+
+type sourceFile struct {
+	fs.File
+}
+
+func (f sourceFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return sourceFileInfo{FileInfo: info}, nil
+}
+
+type sourceDirEntry struct {
+	fs.DirEntry
+}
+
+func (e sourceDirEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return sourceFileInfo{FileInfo: info}, nil
+}
+
+type sourceFileInfo struct {
+	fs.FileInfo
+}
+
+func (i sourceFileInfo) ModTime() time.Time {
+	return sourceModTime
+}
+
+func (i sourceFileInfo) Mode() fs.FileMode {
+	mode := i.FileInfo.Mode()
+	if mode.IsDir() || mode&0111 != 0 {
+		return mode.Type() | 0755
+	}
+	return mode.Type() | 0644
+}
+
+// Sys hides the owner, which tar would otherwise record from the stat result.
+func (i sourceFileInfo) Sys() any {
+	return nil
+}
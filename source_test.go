@@ -0,0 +1,79 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIncludePatterns(t *testing.T) {
+	got := includePatterns([]string{"src/**/*.py", "./pyproject.toml", "/lib/a/b.txt", "*/x"})
+	want := []string{"**", "!src", "!src/**/*.py", "!pyproject.toml", "!lib", "!lib/a", "!lib/a/b.txt", "!*/x"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGitignorePatterns(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		dir     string
+		want    []string
+	}{
+		{
+			name:    "root",
+			content: "# comment\n\n*.pyc\n/build/\n!keep.pyc\nsub/out\n\\#file\n",
+			dir:     ".",
+			want:    []string{"**/*.pyc", "build", "!**/keep.pyc", "sub/out", "**/#file"},
+		},
+		{
+			name:    "subdirectory",
+			content: "*.log\n/dist\n",
+			dir:     "pkg",
+			want:    []string{"pkg/**/*.log", "pkg/dist"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := gitignorePatterns(tc.content, tc.dir); !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSourceArchiveHash(t *testing.T) {
+	dir := fstest.MapFS{
+		"main.py":        {Data: []byte("print('hi')\n")},
+		"notes/todo.txt": {Data: []byte("ship it\n")},
+	}
+	excludes := []string{"notes"}
+	base, err := sourceArchiveHash(dir, excludes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir["notes/todo.txt"] = &fstest.MapFile{Data: []byte("shipped\n")}
+	if got, err := sourceArchiveHash(dir, excludes); err != nil || got != base {
+		t.Errorf("changing an excluded file changed the hash: %s, %v", got, err)
+	}
+
+	dir["main.py"] = &fstest.MapFile{Data: []byte("print('hello')\n")}
+	if got, err := sourceArchiveHash(dir, excludes); err != nil || got == base {
+		t.Errorf("changing an uploaded file kept the hash: %s, %v", got, err)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/server-sdk-go/v2/pkg/cloudagents"
+)
+
+const liveKitCloudURL = "https://cloud.livekit.io"
+
+// workflowRunURL links to the current workflow run, when running in GitHub Actions.
+func workflowRunURL() string {
+	server, repo, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server == "" || repo == "" || runID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/actions/runs/%s", server, repo, runID)
+}
+
+// operationReport is the outcome of an operation and the live state of the
+// agent it ran against, rendered for the job summary and pull request comments.
+type operationReport struct {
+	Operation   string
+	Code        int
+	Duration    time.Duration
+	AgentID     string
+	Release     string
+	Environment string
+	// Agent is nil when the agent does not exist or could not be fetched.
+	Agent *livekit.AgentInfo
+}
+
+func newOperationReport(client *cloudagents.Client, workingDir string, operation string, code int, duration time.Duration) *operationReport {
+	r := &operationReport{Operation: operation, Code: code, Duration: duration, Release: releaseVersion(), Environment: environmentName()}
+	if lkConfig, exists, err := LoadTOMLFile(workingDir, LiveKitTOMLFile); err == nil && exists && lkConfig.HasAgent() {
+		r.AgentID = lkConfig.Agent.ID
+	}
+	if r.AgentID != "" {
+		res, err := client.ListAgents(context.Background(), &livekit.ListAgentsRequest{
+			AgentId: r.AgentID,
+		})
+		if err == nil && len(res.Agents) > 0 {
+			r.Agent = res.Agents[0]
+		}
+	}
+	return r
+}
+
+// markdown renders the report under the given heading.
+func (r *operationReport) markdown(heading string) string {
+	result := "✅ Succeeded"
+	if r.Code != 0 {
+		result = "❌ Failed"
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "%s\n\n", heading)
+	summary.WriteString("| | |\n| --- | --- |\n")
+	fmt.Fprintf(&summary, "| Result | %s |\n", result)
+	if r.Environment != "" {
+		fmt.Fprintf(&summary, "| Environment | %s |\n", r.Environment)
+	}
+	if r.AgentID != "" {
+		fmt.Fprintf(&summary, "| Agent | `%s` |\n", r.AgentID)
+	}
+	if r.Release != "" {
+		fmt.Fprintf(&summary, "| Release | `%s` |\n", r.Release)
+	}
+	if r.Agent != nil {
+		fmt.Fprintf(&summary, "| Version | `%s` |\n", r.Agent.Version)
+		if r.Agent.DeployedAt != nil {
+			fmt.Fprintf(&summary, "| Deployed at | %s |\n", r.Agent.DeployedAt.AsTime().UTC().Format(time.RFC3339))
+		}
+	}
+	fmt.Fprintf(&summary, "| Duration | %s |\n", r.Duration.Round(time.Second))
+	summary.WriteString("\n")
+
+	if r.Agent != nil && len(r.Agent.AgentDeployments) > 0 {
+		summary.WriteString("| Region | Status | Replicas | CPU | Memory |\n")
+		summary.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, deployment := range r.Agent.AgentDeployments {
+			fmt.Fprintf(&summary, "| %s | %s | %d / %d | %s / %s | %s / %s |\n",
+				deployment.Region,
+				deployment.Status,
+				deployment.Replicas,
+				deployment.MaxReplicas,
+				deployment.CurCpu,
+				deployment.CpuLimit,
+				deployment.CurMem,
+				deployment.MemLimit,
+			)
+		}
+		summary.WriteString("\n")
+	}
+
+	links := []string{fmt.Sprintf("[LiveKit Cloud dashboard](%s)", liveKitCloudURL)}
+	if url := workflowRunURL(); url != "" {
+		links = append(links, fmt.Sprintf("[Workflow run](%s)", url))
+	}
+	summary.WriteString(strings.Join(links, " · ") + "\n\n")
+	return summary.String()
+}
+
+// writeOperationSummary adds the operation's result to the job summary.
+func writeOperationSummary(report *operationReport) {
+	heading := fmt.Sprintf("### LiveKit Cloud Agent `%s`", report.Operation)
+	if err := appendStepSummary(report.markdown(heading)); err != nil {
+		log.Warnw("Failed to write step summary", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// teamsNotifier posts notifications to TEAMS_WEBHOOK_URL as Adaptive Cards
+// with the same details as the Slack message. Progress updates are not sent,
+// as incoming webhooks cannot thread them.
+type teamsNotifier struct{}
+
+func (teamsNotifier) name() string { return "teams" }
+
+func (teamsNotifier) configured() bool { return getInputOrEnv("TEAMS_WEBHOOK_URL") != "" }
+
+func (teamsNotifier) supports(event string) bool { return event != eventProgress }
+
+func (teamsNotifier) send(n notification) error {
+	payload := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     teamsAdaptiveCard(n.text(), n.data()),
+		}},
+	}
+	return postWebhook(getInputOrEnv("TEAMS_WEBHOOK_URL"), payload, "")
+}
+
+func teamsAdaptiveCard(text string, d notificationData) map[string]any {
+	body := []map[string]any{
+		{
+			"type":   "TextBlock",
+			"text":   fmt.Sprintf("LiveKit Cloud agent %s %s", d.Operation, d.Event),
+			"size":   "Medium",
+			"weight": "Bolder",
+			"color":  teamsColor(d.Event),
+			"wrap":   true,
+		},
+		{"type": "TextBlock", "text": text, "wrap": true},
+	}
+
+	var facts []map[string]string
+	fact := func(title, value string) {
+		if value != "" {
+			facts = append(facts, map[string]string{"title": title, "value": value})
+		}
+	}
+	fact("Agent", d.Agent.ID)
+	fact("Version", d.Agent.Version)
+	fact("Operation", d.Operation)
+	fact("Environment", d.Environment)
+	if d.Git.CommitURL != "" {
+		fact("Commit", fmt.Sprintf("[%s](%s)", d.Git.ShortCommit, d.Git.CommitURL))
+	} else {
+		fact("Commit", d.Git.ShortCommit)
+	}
+	fact("Actor", d.Git.Actor)
+	if d.Duration > 0 {
+		fact("Duration", d.Duration.String())
+	}
+	for _, region := range d.Agent.Regions {
+		fact(region.Region, fmt.Sprintf("%s (%d / %d replicas)", region.Status, region.Replicas, region.MaxReplicas))
+	}
+	if len(facts) > 0 {
+		body = append(body, map[string]any{"type": "FactSet", "facts": facts})
+	}
+	if summary := d.changeSummary(markdownLink); summary != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": "**Changes**\n\n" + strings.ReplaceAll(summary, "\n", "\n\n"), "wrap": true})
+	}
+	if summary := d.phaseSummary(); summary != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": "**Phases**\n\n" + strings.ReplaceAll(summary, "\n", "\n\n"), "wrap": true})
+	}
+	if len(d.Log) > 0 {
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": "**Log**", "wrap": true},
+			map[string]any{"type": "TextBlock", "text": strings.ReplaceAll(d.logExcerpt(4000), "\n", "\n\n"), "fontType": "Monospace", "size": "Small", "wrap": true},
+		)
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	var actions []map[string]string
+	action := func(title, url string) {
+		if url != "" {
+			actions = append(actions, map[string]string{"type": "Action.OpenUrl", "title": title, "url": url})
+		}
+	}
+	action("View workflow run", d.Git.RunURL)
+	action("Compare commits", d.Links.Compare)
+	action("LiveKit Cloud dashboard", d.Links.Dashboard)
+	if len(actions) > 0 {
+		card["actions"] = actions
+	}
+	return card
+}
+
+func teamsColor(event string) string {
+	switch event {
+	case eventSucceeded, eventRecovered:
+		return "Good"
+	case eventFailed, eventAlert:
+		return "Attention"
+	default:
+		return "Accent"
+	}
+}
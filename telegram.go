@@ -0,0 +1,111 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const maxTelegramMessage = 4096
+
+type telegramNotifier struct{}
+
+func (telegramNotifier) name() string { return "telegram" }
+
+func (telegramNotifier) configured() bool {
+	return getInputOrEnv("TELEGRAM_BOT_TOKEN") != "" && getInputOrEnv("TELEGRAM_CHAT_ID") != ""
+}
+
+// supports matches Slack without a thread, which progress updates need.
+func (telegramNotifier) supports(event string) bool { return event != eventProgress }
+
+func (telegramNotifier) send(n notification) error {
+	payload := map[string]any{
+		"chat_id":                  getInputOrEnv("TELEGRAM_CHAT_ID"),
+		"text":                     telegramMessage(n.text(), n.data()),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	// the token is part of the URL, which the log scrubber hides in errors
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", getInputOrEnv("TELEGRAM_BOT_TOKEN"))
+	return postWebhook(url, payload, "")
+}
+
+// telegramMessage renders the notification in Telegram's HTML markup, with the
+// same details as the Slack blocks.
+func telegramMessage(text string, d notificationData) string {
+	link := func(text, url string) string {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(text))
+	}
+	lines := []string{
+		fmt.Sprintf("<b>LiveKit Cloud agent %s %s</b>", html.EscapeString(d.Operation), d.Event),
+		html.EscapeString(text),
+		"",
+	}
+	field := func(name, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("<b>%s:</b> %s", name, value))
+		}
+	}
+	if d.Agent.ID != "" {
+		field("Agent", "<code>"+html.EscapeString(d.Agent.ID)+"</code>")
+	}
+	if d.Agent.Version != "" {
+		field("Version", "<code>"+html.EscapeString(d.Agent.Version)+"</code>")
+	}
+	field("Operation", html.EscapeString(d.Operation))
+	field("Environment", html.EscapeString(d.Environment))
+	if d.Git.CommitURL != "" {
+		field("Commit", link(d.Git.ShortCommit, d.Git.CommitURL))
+	} else if d.Git.ShortCommit != "" {
+		field("Commit", "<code>"+d.Git.ShortCommit+"</code>")
+	}
+	field("Actor", html.EscapeString(d.Git.Actor))
+	if d.Duration > 0 {
+		field("Duration", d.Duration.String())
+	}
+
+	section := func(name, body string) {
+		if body != "" {
+			lines = append(lines, "", "<b>"+name+"</b>", body)
+		}
+	}
+	var regions []string
+	for _, region := range d.Agent.Regions {
+		regions = append(regions, html.EscapeString(fmt.Sprintf("• %s: %s (%d / %d replicas)", region.Region, region.Status, region.Replicas, region.MaxReplicas)))
+	}
+	section("Regions", strings.Join(regions, "\n"))
+	section("Changes", d.changeSummary(link))
+	section("Phases", html.EscapeString(d.phaseSummary()))
+	if len(d.Log) > 0 {
+		section("Log", "<pre>"+html.EscapeString(d.logExcerpt(2000))+"</pre>")
+	}
+
+	var links []string
+	if d.Git.RunURL != "" {
+		links = append(links, link("View workflow run", d.Git.RunURL))
+	}
+	links = append(links, link("LiveKit Cloud dashboard", d.Links.Dashboard))
+	lines = append(lines, "", strings.Join(links, " · "))
+
+	message := strings.Join(lines, "\n")
+	if len([]rune(message)) > maxTelegramMessage {
+		// cutting the markup could leave a tag open, so only the text is kept
+		return truncate(html.EscapeString(text), maxTelegramMessage)
+	}
+	return message
+}
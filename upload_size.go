@@ -0,0 +1,133 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// defaultMaxUploadSize is the compressed upload size allowed when
+// MAX_UPLOAD_SIZE is not set.
+const defaultMaxUploadSize = 500 * 1000 * 1000
+
+// uploadBreakdownEntries is how many files and directories are listed when the
+// upload is too large.
+const uploadBreakdownEntries = 10
+
+// countingWriter discards what is written to it, counting the bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// sourceEntry is a file or directory of the upload with its uncompressed size.
+type sourceEntry struct {
+	path string
+	size int64
+}
+
+// checkUploadSize fails the action before anything is uploaded when the
+// compressed source of workingDir is larger than MAX_UPLOAD_SIZE, listing the
+// largest files and directories so they can be excluded.
+func checkUploadSize(workingDir string, excludes []string) {
+	limit := getSizeInput("MAX_UPLOAD_SIZE", defaultMaxUploadSize)
+	if limit == 0 {
+		return
+	}
+
+	dir := newSourceFS(workingDir)
+	var w countingWriter
+	if err := writeSourceArchive(dir, excludes, &w); err != nil {
+		// the upload reports the same problem with more context
+		log.Warnw("Failed to measure the upload size", err)
+		return
+	}
+	log.Debugw("Measured upload size", "size", formatSize(w.n), "limit", formatSize(limit))
+	if w.n <= limit {
+		return
+	}
+
+	files, dirs, err := largestSourceEntries(dir, excludes)
+	if err != nil {
+		log.Warnw("Failed to list the largest files of the upload", err)
+	}
+	if len(dirs) > 0 {
+		fmt.Println("Largest directories (uncompressed):")
+		for _, e := range dirs {
+			fmt.Printf("  %10s  %s/\n", formatSize(e.size), e.path)
+		}
+	}
+	if len(files) > 0 {
+		fmt.Println("Largest files (uncompressed):")
+		for _, e := range files {
+			fmt.Printf("  %10s  %s\n", formatSize(e.size), e.path)
+		}
+	}
+	log.Errorw("Upload is larger than MAX_UPLOAD_SIZE, exclude large files with .livekitignore or EXCLUDE", nil,
+		"size", formatSize(w.n),
+		"limit", formatSize(limit),
+	)
+	exit(1)
+}
+
+// largestSourceEntries returns the largest files of the upload and its
+// largest top-level directories, largest first.
+func largestSourceEntries(dir fs.FS, excludes []string) ([]sourceEntry, []sourceEntry, error) {
+	paths, err := sourceFiles(dir, excludes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []sourceEntry
+	dirSizes := make(map[string]int64)
+	for _, p := range paths {
+		info, err := fs.Stat(dir, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, sourceEntry{path: p, size: info.Size()})
+		if top, _, ok := strings.Cut(p, "/"); ok {
+			dirSizes[top] += info.Size()
+		}
+	}
+
+	var dirs []sourceEntry
+	for p, size := range dirSizes {
+		dirs = append(dirs, sourceEntry{path: p, size: size})
+	}
+	return largestEntries(files), largestEntries(dirs), nil
+}
+
+// largestEntries sorts entries largest first, by path on ties, and keeps the
+// first uploadBreakdownEntries.
+func largestEntries(entries []sourceEntry) []sourceEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].size != entries[j].size {
+			return entries[i].size > entries[j].size
+		}
+		return entries[i].path < entries[j].path
+	})
+	if len(entries) > uploadBreakdownEntries {
+		entries = entries[:uploadBreakdownEntries]
+	}
+	return entries
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+)
+
+// webhookPayload is the JSON body posted to WEBHOOK_URL. The fields are
+// documented in the README, keep them in sync.
+type webhookPayload struct {
+	Event           string         `json:"event"`
+	Message         string         `json:"message"`
+	Operation       string         `json:"operation"`
+	Environment     string         `json:"environment,omitempty"`
+	Release         string         `json:"release,omitempty"`
+	Agent           webhookAgent   `json:"agent"`
+	Git             webhookGit     `json:"git"`
+	DurationSeconds float64        `json:"duration_seconds,omitempty"`
+	StartedAt       time.Time      `json:"started_at"`
+	Timestamp       time.Time      `json:"timestamp"`
+	Phases          []phase        `json:"phases"`
+	Changes         webhookChanges `json:"changes"`
+	Links           webhookLinks   `json:"links"`
+	Log             []string       `json:"log"`
+}
+
+type webhookChanges struct {
+	SecretsAdded   []string `json:"secrets_added"`
+	SecretsRemoved []string `json:"secrets_removed"`
+	RegionsAdded   []string `json:"regions_added"`
+	RegionsRemoved []string `json:"regions_removed"`
+	PreviousCommit string   `json:"previous_commit,omitempty"`
+}
+
+type webhookLinks struct {
+	Dashboard string `json:"dashboard"`
+	Compare   string `json:"compare,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	Run       string `json:"run,omitempty"`
+}
+
+type webhookAgent struct {
+	ID      string          `json:"id,omitempty"`
+	Version string          `json:"version,omitempty"`
+	Status  string          `json:"status,omitempty"`
+	Regions []webhookRegion `json:"regions"`
+}
+
+type webhookRegion struct {
+	Region      string `json:"region"`
+	Status      string `json:"status"`
+	Replicas    int32  `json:"replicas"`
+	MaxReplicas int32  `json:"max_replicas"`
+}
+
+type webhookGit struct {
+	Repository string `json:"repository,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+	Actor      string `json:"actor,omitempty"`
+	RunURL     string `json:"run_url,omitempty"`
+}
+
+// webhookNotifier posts every notification, progress updates included, to
+// WEBHOOK_URL, for automation to act on.
+type webhookNotifier struct{}
+
+func (webhookNotifier) name() string { return "webhook" }
+
+func (webhookNotifier) configured() bool { return getInputOrEnv("WEBHOOK_URL") != "" }
+
+func (webhookNotifier) supports(event string) bool { return true }
+
+func (webhookNotifier) send(n notification) error {
+	d := n.data()
+	payload := webhookPayload{
+		Event:       d.Event,
+		Message:     n.text(),
+		Operation:   d.Operation,
+		Environment: d.Environment,
+		Release:     d.Release,
+		Agent: webhookAgent{
+			ID:      d.Agent.ID,
+			Version: d.Agent.Version,
+			Status:  d.Agent.Status,
+			Regions: []webhookRegion{},
+		},
+		Git: webhookGit{
+			Repository: d.Git.Repository,
+			Commit:     d.Git.Commit,
+			Ref:        d.Git.Ref,
+			Actor:      d.Git.Actor,
+			RunURL:     d.Git.RunURL,
+		},
+		DurationSeconds: n.Duration.Round(time.Millisecond).Seconds(),
+		StartedAt:       d.StartedAt,
+		Timestamp:       time.Now().UTC(),
+		Phases:          append([]phase{}, phases...),
+		Changes: webhookChanges{
+			SecretsAdded:   append([]string{}, d.Changes.SecretsAdded...),
+			SecretsRemoved: append([]string{}, d.Changes.SecretsRemoved...),
+			RegionsAdded:   append([]string{}, d.Changes.RegionsAdded...),
+			RegionsRemoved: append([]string{}, d.Changes.RegionsRemoved...),
+			PreviousCommit: d.Changes.PreviousCommit,
+		},
+		Links: webhookLinks{
+			Dashboard: d.Links.Dashboard,
+			Compare:   d.Links.Compare,
+			Commit:    d.Links.Commit,
+			Run:       d.Links.Run,
+		},
+		Log: append([]string{}, d.Log...),
+	}
+	for _, region := range d.Agent.Regions {
+		payload.Agent.Regions = append(payload.Agent.Regions, webhookRegion{
+			Region:      region.Region,
+			Status:      region.Status,
+			Replicas:    region.Replicas,
+			MaxReplicas: region.MaxReplicas,
+		})
+	}
+
+	return postWebhook(getInputOrEnv("WEBHOOK_URL"), payload, getInputOrEnv("WEBHOOK_SECRET"))
+}